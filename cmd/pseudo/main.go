@@ -50,7 +50,7 @@ func main() {
 	defer out.Close()
 
 	// loop through args and report output
-	s := p.NewSession(p.Context{lowestlabel, fifobuckets, displaycut})
+	s := p.NewSession(p.Context{LowestLabel: lowestlabel, FifoBuckets: fifobuckets, DisplayCut: displaycut})
 	for i, arg := range args {
 		if arg == "stdin" {
 			in = os.Stdin
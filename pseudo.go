@@ -65,20 +65,51 @@
 //	n 1
 //	n 3
 //
+// Context values should be set using keyed fields - Context{DisplayCut:true} -
+// since new switches are added to Context over time and positional literals
+// such as Context{false, false, true} will then no longer compile.
+//
 package pseudo
 
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+// ErrNoSolution is returned by methods that report on a solution when
+// the Session has not yet processed a data set.
+var ErrNoSolution = errors.New("no solution - Session has not processed any data")
+
+// ErrInfeasibleSolution is returned if Context.StrictFeasibility is set and
+// the computed flows violate a capacity or flow balance constraint.
+var ErrInfeasibleSolution = errors.New("solution violates capacity or flow balance constraints")
+
+// ErrNoGraph is returned by RunWriter if the Session has no graph loaded that
+// has not already been solved.
+var ErrNoGraph = errors.New("no graph - Session has no unsolved graph loaded")
+
+// ErrCapacityOverflow is returned if the capacities of the arcs out of the
+// source, or into the sink, sum past the largest int - 2^31-1 on 32-bit
+// targets. If the excess of a single node overflows, the error names it.
+var ErrCapacityOverflow = errors.New("source or sink capacity overflows int")
+
+// ErrNoFeasibleFlow is returned if no flow gives every mandatory arc,
+// see SessionInitializer.AddMustUseArc, its minimum flow.
+var ErrNoFeasibleFlow = errors.New("no feasible flow - mandatory arcs can't all carry their minimum flow")
+
 // Session is the runtime container.
 type Session struct {
 	// the runtime context
@@ -92,25 +123,100 @@ type Session struct {
 	labelCount                      []uint
 	numNodes, numArcs, source, sink uint
 	// stats and timer
-	stats statistics
+	stats Stats
 	times timer
+	// set when process has computed a solution
+	solved bool
+	// the min cut value of the solution, see MaxFlow
+	maxFlow int
+	// set when a graph has been loaded but not yet solved
+	loaded bool
+	// set when flowPhaseOne was cancelled; the solve can be resumed
+	interrupted bool
+	// with Context.CaptureRelabelOrder
+	relabels []Relabel
+	// warm start, see pseudo_initflow.go
+	initialFlow []A
+	splitArcs   []splitArc
+	// set when UpdateArcCapacity changed a solved graph; see Resolve
+	updated bool
+	// validate input for RunStrict
+	strict bool
+	// only parse and check the input, see ValidateDimacs
+	validate bool
+	// the input line buffer, see SetScanBufferSize
+	scanBufSize int
+	// solver progress, see SetLogger and SetProgressFunc
+	logger   Logger
+	progress func(processed, total uint)
+	// if > 0, nodes past inputNodes are a super-source or super-sink; see Context.MultiTerminal
+	inputNodes uint
 }
 
 // Context provides optional switches that can be used to configure
 // the Session runtime.
 type Context struct {
-	LowestLabel bool
-	FifoBuckets bool
-	DisplayCut  bool // report minimun cut set instead of graph flows
+	LowestLabel         bool
+	FifoBuckets         bool
+	DisplayCut          bool // report minimun cut set instead of graph flows
+	StrictFeasibility   bool // return ErrInfeasibleSolution rather than reporting constraint violations
+	SkipBadLines        bool // skip malformed input lines rather than returning an error
+	SinkMinimalCut      bool // report the min cut with the smallest sink set; see Cut
+	CaptureRelabelOrder bool // record each relabel for RelabelHistory
+	MaxFlowRecords      int  // if > 0, report only the first MaxFlowRecords "f" lines
+	MergeParallelArcs   bool // load arcs with the same from and to as one arc with the sum of their capacities
+	CutSummaryOnly      bool // report only the max flow and the sizes of the sides of the min cut
+	UseArcPrefix        bool // report flows as "a SRC DST FLOW" lines, as C source does, rather than "f" lines
+	SortOutput          bool // report flows ordered by SRC and then DST rather than in arcList order
+	MultiTerminal       bool // allow several 's' and 't' n lines; see below
+	Undirected          bool // load each arc as an undirected edge; see below
+	// If set, skipped lines and disconnected graphs, see IsConnected, are reported here.
+	WarnWriter io.Writer `json:"-"`
+	// If set, called for each push of excess from node 'from' to its parent
+	// 'to' in the normalized tree; 'upward' is whether the push is along the
+	// arc. It is called once per Stats.Pushes.
+	PushFn func(from, to uint, amount int, upward bool) `json:"-"`
+	// If set, each line of DIMACS input read by a Run method is passed
+	// through LinePreprocessor, with its EOL and surrounding white space
+	// removed, before it is split into fields and parsed. Lines that it
+	// returns empty are skipped; line numbers in errors count the input lines.
+	// 'line' is overwritten by the next line, so it must not be retained.
+	LinePreprocessor func(line []byte) []byte `json:"-"`
+	// With MultiTerminal the input may have several sources and sinks, as
+	// "n <node> s [capacity]" and "n <node> t [capacity]" lines. Each source
+	// is fed from a super-source, node numNodes+1, by an arc of 'capacity'
+	// or, if omitted, the total capacity of the arcs out of the source;
+	// sinks drain to a super-sink likewise. The super nodes and their arcs
+	// are left out of the flows and cut reported by Run*, Flows, Cut and
+	// SinkSet. MultiTerminal doesn't apply to RunNA.
+	//
+	// With Undirected each 'a' line, or SessionInitializer.AddArc, is an edge
+	// that is loaded as two opposing arcs with its capacity, and numArcs of the
+	// 'p' line, or Init, counts edges. Flows and the "f" lines then have a
+	// record for each arc; the net flow on an edge is the difference of the
+	// two. An edge across the min cut counts once toward its capacity, by the
+	// arc leaving the source set, which is the one CutArcs reports.
+	// Undirected doesn't apply to RunNA.
+}
+
+// Logger receives debug lines about the progress of a solve; see SetLogger.
+// *log.Logger implements it.
+type Logger interface {
+	Printf(format string, args ...interface{})
 }
 
-// statistics
-type statistics struct {
+// Stats are the processing statistics of a solve; see StatsJSON. Pushes,
+// Relabels and ArcScans measure the work done by the solver.
+type Stats struct {
 	Pushes   uint `json:"pushes"`
 	Mergers  uint `json:"mergers"`
 	Relabels uint `json:"relabels"`
 	Gaps     uint `json:"gaps"`
 	ArcScans uint `json:"arcScans"`
+	// the most nodes simultaneously in a strong root bucket
+	PeakBucketSize uint `json:"peakBucketSize"`
+	// input lines skipped with Context.SkipBadLines
+	SkippedLines uint `json:"skippedLines"`
 }
 
 // timing info in case someone wants it as in C source main()
@@ -118,6 +224,52 @@ type timer struct {
 	start, readfile, initialize, flow, recflow time.Time
 }
 
+// Timings are the durations of the processing steps of a solve; see TimerJSON.
+type Timings struct {
+	ReadDimacsFile       time.Duration `json:"readDimacsFile"`
+	SimpleInitialization time.Duration `json:"simpleInitialization"`
+	FlowPhaseOne         time.Duration `json:"flowPhaseOne"`
+	RecoverFlow          time.Duration `json:"recoverFlow"`
+	Total                time.Duration `json:"total"`
+}
+
+// Timings returns the step durations of the last solve; TimerJSON reports
+// the same durations as strings.
+func (s *Session) Timings() Timings {
+	return Timings{
+		ReadDimacsFile:       s.times.readfile.Sub(s.times.start),
+		SimpleInitialization: s.times.initialize.Sub(s.times.readfile),
+		FlowPhaseOne:         s.times.flow.Sub(s.times.initialize),
+		RecoverFlow:          s.times.recflow.Sub(s.times.flow),
+		Total:                s.times.recflow.Sub(s.times.start),
+	}
+}
+
+// Result is the solution of a Run as a structure rather than the DIMACS
+// text. Flows has one entry per arc, in the order of the "f" lines of
+// Run; Cut is the source set of the minimum cut.
+type Result struct {
+	Header  string  `json:"header,omitempty"`
+	MaxFlow int     `json:"maxflow"`
+	Flows   []A     `json:"flows"`
+	Cut     []uint  `json:"cut"`
+	Stats   Stats   `json:"stats"`
+	Timings Timings `json:"timings"`
+}
+
+// newResult returns the Result of the last solve.
+func (s *Session) newResult(header string) *Result {
+	res := &Result{
+		Header:  header,
+		MaxFlow: s.nodeExcess()[s.sink-1],
+		Flows:   s.Flows(),
+		Cut:     s.Cut(),
+		Stats:   s.stats,
+		Timings: s.Timings(),
+	}
+	return res
+}
+
 // NewSession returns a pseudo Session initialized to the specified Context.
 // specified by 'c'. Examples:
 //	s := NewSession(Context{})                                 // use default runtime settings
@@ -125,12 +277,138 @@ type timer struct {
 //
 func NewSession(c Context) *Session {
 	s := &Session{ctx: c}
+	s.seedLabels()
+	return s
+}
+
+// Option sets a Context field for NewSessionOpts.
+type Option func(*Context)
+
+// WithLowestLabel sets Context.LowestLabel.
+func WithLowestLabel() Option { return func(c *Context) { c.LowestLabel = true } }
+
+// WithFifoBuckets sets Context.FifoBuckets.
+func WithFifoBuckets() Option { return func(c *Context) { c.FifoBuckets = true } }
+
+// WithDisplayCut sets Context.DisplayCut.
+func WithDisplayCut() Option { return func(c *Context) { c.DisplayCut = true } }
+
+// NewSessionOpts returns a pseudo Session with a Context built by applying
+// 'opts', in order, to the default Context. Example:
+//	s := NewSessionOpts(WithLowestLabel(), WithDisplayCut())
+//
+func NewSessionOpts(opts ...Option) *Session {
+	var c Context
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return NewSession(c)
+}
+
+// NewSessionFromJSON returns a pseudo Session with the Context of 'cfg', a
+// JSON object as returned by ConfigJSON; it reverses ConfigJSON. Unknown
+// keys are an error. Fields that ConfigJSON omits, e.g., WarnWriter, are
+// left unset.
+func NewSessionFromJSON(cfg []byte) (*Session, error) {
+	var c Context
+	dec := json.NewDecoder(bytes.NewReader(cfg))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, err
+	}
+	return NewSession(c), nil
+}
+
+// SetLogger sets 'l' to receive debug lines as the Session solves: gaps,
+// mergers of trees and every relabelLogInterval relabels. Without a Logger,
+// or with SetLogger(nil), nothing is logged and nothing is formatted.
+func (s *Session) SetLogger(l Logger) {
+	s.logger = l
+}
+
+// SetProgressFunc sets 'f' to be called as the flow phase of a solve
+// processes strong roots: every ctxCheckInterval roots and once when the
+// phase ends, with the number of roots processed so far and the number of
+// nodes. A node can be a strong root more than once, so 'processed' can pass
+// 'total'; it is counted from 0 again when a cancelled solve is resumed.
+// SetProgressFunc(nil) removes it.
+func (s *Session) SetProgressFunc(f func(processed, total uint)) {
+	s.progress = f
+}
+
+// relabelLogInterval is the number of relabels between progress lines to
+// the Logger.
+var relabelLogInterval uint = 1 << 16
+
+// relabelCounted logs the relabel count every relabelLogInterval relabels.
+func (s *Session) relabelCounted() {
+	if s.logger != nil && s.stats.Relabels%relabelLogInterval == 0 {
+		s.logger.Printf("relabels: %d", s.stats.Relabels)
+	}
+}
+
+// seedLabels sets the starting strong root label for the Context.
+func (s *Session) seedLabels() {
+	s.lowestStrongLabel, s.highestStrongLabel = 0, 0
 	if s.ctx.LowestLabel {
 		s.lowestStrongLabel = 1
 	} else {
 		s.highestStrongLabel = 1
 	}
-	return s
+}
+
+// Reset clears the graph, the solution, the stats and any initial flow from
+// the Session, and re-seeds the solver labels, so the Session is as returned
+// by NewSession with its current Context. Run* and the SessionInitializer
+// reset the graph and solver state when a new graph is loaded, so successive
+// runs on one Session are independent without calling Reset.
+func (s *Session) Reset() {
+	s.reset()
+	s.stats = Stats{}
+	s.times = timer{}
+	s.initialFlow = nil
+}
+
+// reset clears the graph and the solver state before a graph is loaded;
+// the stats and an initial flow for the next solve are kept.
+func (s *Session) reset() {
+	s.seedLabels()
+	// nothing outside the Session refers to its nodes and arcs
+	for _, n := range s.adjacencyList {
+		if n != nil {
+			nodePool.Put(n)
+		}
+	}
+	for _, a := range s.arcList {
+		if a != nil {
+			arcPool.Put(a)
+		}
+	}
+	s.adjacencyList = nil
+	s.strongRoots = nil
+	s.arcList = nil
+	s.labelCount = nil
+	s.numNodes, s.numArcs, s.source, s.sink = 0, 0, 0, 0
+	s.solved, s.loaded, s.interrupted, s.updated = false, false, false, false
+	s.maxFlow = 0
+	s.splitArcs = nil
+	s.relabels = nil
+	s.inputNodes = 0
+}
+
+// SetContext replaces the Session Context between runs, e.g., to solve the
+// next data set with LowestLabel or FifoBuckets changed. The solver labels
+// are re-seeded for the new Context and any solution is discarded; a loaded
+// graph that has not been solved is kept. It returns an error, and leaves
+// the Context unchanged, if a solve was interrupted and can still be resumed.
+func (s *Session) SetContext(c Context) error {
+	if s.interrupted {
+		return errors.New("can't change the Context of an interrupted solve")
+	}
+	s.ctx = c
+	s.seedLabels()
+	s.solved = false
+	return nil
 }
 
 // ConfigJSON returns the runtime context settings as a JSON object.
@@ -139,9 +417,30 @@ func (s *Session) ConfigJSON() string {
 	return string(j)
 }
 
-// StatsJSON returns the runtime stats as a JSON object.
+// Stats returns a copy of the runtime stats; StatsJSON is the same as JSON,
+// with WorkPerNode added.
+func (s *Session) Stats() Stats {
+	return s.stats
+}
+
+// WorkPerNode returns (Pushes + Relabels + ArcScans) / number of nodes, the
+// work of the last solve normalized by the size of the graph, to compare the
+// difficulty of instances of different sizes. It returns 0 if no graph has
+// been loaded.
+func (s *Session) WorkPerNode() float64 {
+	if s.numNodes == 0 {
+		return 0
+	}
+	return float64(s.stats.Pushes+s.stats.Relabels+s.stats.ArcScans) / float64(s.numNodes)
+}
+
+// StatsJSON returns the runtime stats and WorkPerNode as a JSON object.
 func (s *Session) StatsJSON() string {
-	j, _ := json.Marshal(s.stats)
+	data := struct {
+		Stats
+		WorkPerNode float64 `json:"workPerNode"`
+	}{s.stats, s.WorkPerNode()}
+	j, _ := json.Marshal(data)
 	return string(j)
 }
 
@@ -150,6 +449,7 @@ func (s *Session) StatsJSON() string {
 // Note: the file initialization and result marshaling times are not
 // included in result.
 func (s *Session) TimerJSON() string {
+	t := s.Timings()
 	data := struct {
 		ReadDimacsFile       string `json:"readDimacsFile"`
 		SimpleInitialization string `json:"simpleInitialization"`
@@ -157,11 +457,11 @@ func (s *Session) TimerJSON() string {
 		RecoverFlow          string `json:"recoverFlow"`
 		Total                string `json:"total"`
 	}{
-		s.times.readfile.Sub(s.times.start).String(),
-		s.times.initialize.Sub(s.times.readfile).String(),
-		s.times.flow.Sub(s.times.initialize).String(),
-		s.times.recflow.Sub(s.times.flow).String(),
-		s.times.recflow.Sub(s.times.start).String(),
+		t.ReadDimacsFile.String(),
+		t.SimpleInitialization.String(),
+		t.FlowPhaseOne.String(),
+		t.RecoverFlow.String(),
+		t.Total.String(),
 	}
 	j, _ := json.Marshal(data)
 	return string(j)
@@ -174,12 +474,20 @@ type arc struct {
 	flow      int // in source: uint
 	capacity  int // in source: uint
 	direction uint
+	lower     int // minimum flow of a mandatory arc, see pseudo_mandatory.go
 }
 
 // static inline void
 // pushUpward (Arc *currentArc, Node *child, Node *parent, const uint resCap)
 func (s *Session) pushUpward(a *arc, child, parent *node, resCap int) {
 	s.stats.Pushes++
+	if s.ctx.PushFn != nil {
+		amount := child.excess
+		if resCap < amount {
+			amount = resCap
+		}
+		s.ctx.PushFn(child.number, parent.number, amount, true)
+	}
 	if resCap >= child.excess {
 		parent.excess += child.excess
 		a.flow += child.excess
@@ -205,6 +513,13 @@ func (s *Session) pushUpward(a *arc, child, parent *node, resCap int) {
 // pushDownward (Arc *currentArc, Node *child, Node *parent, uint flow)
 func (s *Session) pushDownward(a *arc, child, parent *node, flow int) {
 	s.stats.Pushes++
+	if s.ctx.PushFn != nil {
+		amount := child.excess
+		if flow < amount {
+			amount = flow
+		}
+		s.ctx.PushFn(child.number, parent.number, amount, false)
+	}
 
 	if flow >= child.excess {
 		parent.excess += child.excess
@@ -244,12 +559,28 @@ type node struct {
 	visited         uint
 }
 
+// nodePool and arcPool recycle the nodes and arcs of a graph when the
+// Session is reset, so that solving many graphs doesn't allocate for each.
+var (
+	nodePool = sync.Pool{New: func() interface{} { return new(node) }}
+	arcPool  = sync.Pool{New: func() interface{} { return new(arc) }}
+)
+
 // make sure everything gets allocated
 func (s *Session) newNode(number uint) *node {
-	return &node{
+	n := nodePool.Get().(*node)
+	*n = node{
 		number: number,
 		// outOfTree: make([]*arc, int(s.numArcs)),
 	}
+	return n
+}
+
+// newArc returns a zeroed arc with 'direction'.
+func newArc(direction uint) *arc {
+	a := arcPool.Get().(*arc)
+	*a = arc{direction: direction}
+	return a
 }
 
 // #ifdef LOWEST_LABEL
@@ -263,12 +594,15 @@ func (s *Session) getLowestStrongRoot() *node {
 		for s.strongRoots[0].start != nil {
 			strongRoot = s.strongRoots[0].start
 			s.strongRoots[0].start = strongRoot.next
+			s.strongRoots[0].size--
 			strongRoot.next = nil
+			s.relabeled(strongRoot, strongRoot.label, 1, false)
 			strongRoot.label = uint(1)
 
 			s.labelCount[0]--
 			s.labelCount[1]++
 			s.stats.Relabels++
+			s.relabelCounted()
 
 			s.addToStrongBucket(strongRoot, s.strongRoots[strongRoot.label])
 		}
@@ -281,11 +615,15 @@ func (s *Session) getLowestStrongRoot() *node {
 
 			if s.labelCount[i-1] == 0 {
 				s.stats.Gaps++
+				if s.logger != nil {
+					s.logger.Printf("gap: no nodes with label %d below strong roots with label %d", i-1, i)
+				}
 				return nil
 			}
 
 			strongRoot = s.strongRoots[i].start
 			s.strongRoots[i].start = strongRoot.next
+			s.strongRoots[i].size--
 			strongRoot.next = nil
 			return strongRoot
 		}
@@ -299,7 +637,7 @@ func (s *Session) getLowestStrongRoot() *node {
 // getHighestStrongRoot (void)
 func (s *Session) getHighestStrongRoot() *node {
 	var i uint
-	strongRoot := s.newNode(0)
+	var strongRoot *node
 
 	for i = s.highestStrongLabel; i > 0; i-- {
 
@@ -308,14 +646,19 @@ func (s *Session) getHighestStrongRoot() *node {
 			if s.labelCount[i-1] > 0 {
 				strongRoot = s.strongRoots[i].start
 				s.strongRoots[i].start = strongRoot.next
+				s.strongRoots[i].size--
 				strongRoot.next = nil
 				return strongRoot
 			}
 
+			if s.logger != nil {
+				s.logger.Printf("gap: no nodes with label %d, lifting %d strong roots with label %d", i-1, s.strongRoots[i].size, i)
+			}
 			for s.strongRoots[i].start != nil {
 				s.stats.Gaps++
 				strongRoot = s.strongRoots[i].start
 				s.strongRoots[i].start = strongRoot.next
+				s.strongRoots[i].size--
 				s.liftAll(strongRoot)
 			}
 		}
@@ -328,11 +671,14 @@ func (s *Session) getHighestStrongRoot() *node {
 	for s.strongRoots[0].start != nil {
 		strongRoot = s.strongRoots[0].start
 		s.strongRoots[0].start = strongRoot.next
+		s.strongRoots[0].size--
+		s.relabeled(strongRoot, strongRoot.label, 1, false)
 		strongRoot.label = 1
 
 		s.labelCount[0]--
 		s.labelCount[1]++
 		s.stats.Relabels++
+		s.relabelCounted()
 
 		s.addToStrongBucket(strongRoot, s.strongRoots[strongRoot.label])
 	}
@@ -341,6 +687,7 @@ func (s *Session) getHighestStrongRoot() *node {
 
 	strongRoot = s.strongRoots[1].start
 	s.strongRoots[1].start = strongRoot.next
+	s.strongRoots[1].size--
 	strongRoot.next = nil
 
 	return strongRoot
@@ -413,6 +760,9 @@ func (s *Session) merge(n, child *node, newArc *arc) {
 	newParent := n
 
 	s.stats.Mergers++ // unlike C source always calc stats
+	if s.logger != nil {
+		s.logger.Printf("merge: tree of node %d under node %d", child.number, n.number)
+	}
 
 	for current.parent != nil {
 		oldArc = current.arcToParent
@@ -553,11 +903,13 @@ func (s *Session) checkChildren(n *node) {
 		}
 	}
 
+	s.relabeled(n, n.label, n.label+1, false)
 	s.labelCount[n.label]--
 	n.label++
 	s.labelCount[n.label]++
 
 	s.stats.Relabels++ // Always collect stats
+	s.relabelCounted()
 
 	n.nextArc = 0
 }
@@ -571,6 +923,7 @@ func (s *Session) liftAll(n *node) {
 
 	current.nextScan = current.childList
 
+	s.relabeled(current, current.label, s.numNodes, true)
 	s.labelCount[current.label]--
 	current.label = s.numNodes
 
@@ -581,13 +934,43 @@ func (s *Session) liftAll(n *node) {
 			current = temp
 			current.nextScan = current.childList
 
+			s.relabeled(current, current.label, s.numNodes, true)
 			s.labelCount[current.label]--
 			current.label = s.numNodes
 		}
 	}
 }
 
+// Relabel is a change of a node's label during a solve; see RelabelHistory.
+type Relabel struct {
+	Node, OldLabel, NewLabel uint
+	// Lift is set if the node was lifted to numNodes because of a gap
+	Lift bool
+}
+
+// relabeled records the relabel of 'n' if Context.CaptureRelabelOrder is set.
+func (s *Session) relabeled(n *node, oldLabel, newLabel uint, lift bool) {
+	if s.ctx.CaptureRelabelOrder {
+		s.relabels = append(s.relabels, Relabel{n.number, oldLabel, newLabel, lift})
+	}
+}
+
+// RelabelHistory returns the relabels of the last solve in the order they
+// occurred, if Context.CaptureRelabelOrder was set; otherwise it returns nil.
+// The relabels without Lift set are those counted by the Relabels stat;
+// the others are the nodes lifted out of the solve by gaps with the highest
+// label algorithm. Replaying the history from the initial labels - 1 for
+// nodes with excess after initialization, 0 for the other nodes besides the
+// source - gives the final labels.
+func (s *Session) RelabelHistory() []Relabel {
+	return s.relabels
+}
+
 func (s *Session) addToStrongBucket(n *node, rootBucket *root) {
+	rootBucket.size++
+	if rootBucket.size > s.stats.PeakBucketSize {
+		s.stats.PeakBucketSize = rootBucket.size
+	}
 	if s.ctx.FifoBuckets {
 		if rootBucket.start != nil {
 			rootBucket.end.next = n
@@ -699,72 +1082,37 @@ func (n *node) decompose(source uint, iteration *uint) {
 type root struct {
 	start *node
 	end   *node
+	size  uint // number of nodes in the bucket, for stats
 }
 
 // ========================== functions implementing solution logic ============================
 
 // static void
 // checkOptimality (const uint gap)
-// Internalize "gap" as in RecoverFlow.
+// Internalize "gap" as in RecoverFlow; the cut value is computed by minCut
+// when the solve completes.
 func (s *Session) checkOptimality(w io.Writer) error {
-	// setting gap value is taken out of main() in C source code
-	var gap uint
-	if s.ctx.LowestLabel {
-		gap = s.lowestStrongLabel
-	} else {
-		gap = s.numNodes
-	}
-
-	var i uint
-	var mincut int
-	// in source: excess := make([]uint, numNodes)
-	excess := make([]int, s.numNodes)
+	mincut := s.maxFlow
+	violations, optimal := s.verify()
 
-	check := true
 	var err error
-	for i = 0; i < s.numArcs; i++ {
-		if s.arcList[i].from.label >= gap && s.arcList[i].to.label < gap {
-			mincut += s.arcList[i].capacity
-		}
-		if s.arcList[i].flow > s.arcList[i].capacity || s.arcList[i].flow < 0 {
-			check = false
-			if _, err = w.Write([]byte(fmt.Sprintf("c Capacity constraint violated on arc (%d, %d). Flow = %d, capacity = %d\n",
-				s.arcList[i].from.number,
-				s.arcList[i].to.number,
-				s.arcList[i].flow,
-				s.arcList[i].capacity))); err != nil {
-				return err
-			}
-		}
-		excess[s.arcList[i].from.number-1] -= s.arcList[i].flow
-		excess[s.arcList[i].to.number-1] += s.arcList[i].flow
-	}
-	for i = 0; i < s.numNodes; i++ {
-		if i != s.source-1 && i != s.sink-1 {
-			if excess[i] != 0 {
-				check = false
-				if _, err = w.Write([]byte(fmt.Sprintf("c Flow balance constraint violated in node %d. Excess = %d\n",
-					i+1,
-					excess[i]))); err != nil {
-					return err
-				}
-			}
+	for _, v := range violations {
+		if _, err = w.Write([]byte("c " + v + "\n")); err != nil {
+			return err
 		}
 	}
-	if check {
+	if len(violations) == 0 {
 		if _, err = w.Write([]byte("c \nc Solution checks as feasible\n")); err != nil {
 			return err
 		}
 	}
 
-	check = true
-	if excess[s.sink-1] != mincut {
-		check = false
-		if _, err = w.Write([]byte("c \nc Flow is not optimal - max flow does not equal min cut\n")); err != nil {
+	if !optimal {
+		if _, err = w.Write([]byte("c \nc " + notOptimal + "\n")); err != nil {
 			return err
 		}
 	}
-	if check {
+	if optimal {
 		if _, err = w.Write([]byte("c \nc Solution checks as optimal\nc \nc Solution\n")); err != nil {
 			return err
 		}
@@ -776,6 +1124,146 @@ func (s *Session) checkOptimality(w io.Writer) error {
 	return nil
 }
 
+// notOptimal is the checkOptimality comment, and Verify violation, for a
+// flow into the sink that differs from the min cut.
+const notOptimal = "Flow is not optimal - max flow does not equal min cut"
+
+// verify returns the capacity and flow balance violations of the solution
+// and whether the flow into the sink equals the min cut.
+func (s *Session) verify() (violations []string, optimal bool) {
+	excess := s.nodeExcess()
+	return s.violations(excess), excess[s.sink-1] == s.maxFlow
+}
+
+// Verify checks the solution as it is checked for the "Solution checks as
+// feasible" and "Solution checks as optimal" comments of Run, and returns the
+// result rather than writing it: 'feasible' is whether no capacity or flow
+// balance constraint is violated, 'optimal' whether the flow into the sink
+// equals the min cut, and 'violations' the comments that Run writes when the
+// checks fail. It returns ErrNoSolution if the Session has not processed any
+// data.
+func (s *Session) Verify() (feasible bool, optimal bool, violations []string, err error) {
+	if !s.solved {
+		return false, false, nil, ErrNoSolution
+	}
+
+	violations, optimal = s.verify()
+	feasible = len(violations) == 0
+	if !optimal {
+		violations = append(violations, notOptimal)
+	}
+	return feasible, optimal, violations, nil
+}
+
+// nodeExcess returns the inflow less the outflow of each node; the
+// excess of node number 'n' is at index n-1.
+func (s *Session) nodeExcess() []int {
+	// in source: excess := make([]uint, numNodes)
+	excess := make([]int, s.numNodes)
+	for i := uint(0); i < s.numArcs; i++ {
+		excess[s.arcList[i].from.number-1] -= s.arcList[i].flow
+		excess[s.arcList[i].to.number-1] += s.arcList[i].flow
+	}
+	return excess
+}
+
+// gap returns the label value that separates the source set of the
+// minimum cut from the sink set; the setting of gap is taken out of
+// main() in C source code.
+func (s *Session) gap() uint {
+	if s.ctx.LowestLabel {
+		return s.lowestStrongLabel
+	}
+	return s.numNodes
+}
+
+// sourceSet returns, indexed by node number - 1, whether a node is in the
+// source set of the minimum cut. By default the set is taken from the node
+// labels; with Context.SinkMinimalCut it is every node that can't reach the
+// sink in the residual graph, and with mandatory arcs it is every node the
+// source can reach in the residual graph.
+func (s *Session) sourceSet() []bool {
+	if s.ctx.SinkMinimalCut {
+		set := s.residualReach(s.sink, true)
+		for i := range set {
+			set[i] = !set[i]
+		}
+		return set
+	}
+	if s.hasLowerBounds() {
+		// not solved with labels, see solveLowerBounds
+		return s.residualReach(s.source, false)
+	}
+
+	gap := s.gap()
+	set := make([]bool, s.numNodes)
+	for i := uint(0); i < s.numNodes; i++ {
+		set[i] = s.adjacencyList[i].label >= gap
+	}
+	return set
+}
+
+// minCut returns the capacity of the arcs crossing from the source set
+// to the sink set of the minimum cut, less the minimum flow of mandatory
+// arcs crossing back.
+func (s *Session) minCut() int {
+	return s.cutCapacity(s.sourceSet())
+}
+
+// cutCapacity is minCut for the cut with source set 'set'.
+func (s *Session) cutCapacity(set []bool) int {
+	var mincut int
+	for i := uint(0); i < s.numArcs; i++ {
+		from, to := set[s.arcList[i].from.number-1], set[s.arcList[i].to.number-1]
+		if from && !to {
+			mincut += s.arcList[i].capacity
+		} else if !from && to {
+			mincut -= s.arcList[i].lower
+		}
+	}
+	return mincut
+}
+
+// violations returns a description of each arc whose flow violates its
+// capacity constraint and of each node, other than source and sink, whose
+// 'excess' violates the flow balance constraint.
+func (s *Session) violations(excess []int) []string {
+	var v []string
+	for i := uint(0); i < s.numArcs; i++ {
+		if s.arcList[i].flow > s.arcList[i].capacity || s.arcList[i].flow < 0 {
+			v = append(v, fmt.Sprintf("Capacity constraint violated on arc (%d, %d). Flow = %d, capacity = %d",
+				s.arcList[i].from.number,
+				s.arcList[i].to.number,
+				s.arcList[i].flow,
+				s.arcList[i].capacity))
+		}
+		if s.arcList[i].flow < s.arcList[i].lower {
+			v = append(v, fmt.Sprintf("Minimum flow constraint violated on arc (%d, %d). Flow = %d, minimum = %d",
+				s.arcList[i].from.number,
+				s.arcList[i].to.number,
+				s.arcList[i].flow,
+				s.arcList[i].lower))
+		}
+	}
+	for i := uint(0); i < s.numNodes; i++ {
+		if i != s.source-1 && i != s.sink-1 && excess[i] != 0 {
+			v = append(v, fmt.Sprintf("Flow balance constraint violated in node %d. Excess = %d",
+				i+1,
+				excess[i]))
+		}
+	}
+	return v
+}
+
+// checkFeasibility returns ErrInfeasibleSolution if the current flows
+// violate any capacity or flow balance constraint.
+func (s *Session) checkFeasibility() error {
+	if len(s.violations(s.nodeExcess())) > 0 {
+		return ErrInfeasibleSolution
+	}
+	return nil
+}
+
 // static void
 // displayCut (const uint gap)
 func (s *Session) displayCut(w io.Writer) error {
@@ -794,189 +1282,743 @@ func (s *Session) displayCut(w io.Writer) error {
 	return nil
 }
 
-func (s *Session) Cut() []uint {
-	var gap uint
-	if s.ctx.LowestLabel {
-		gap = s.lowestStrongLabel
-	} else {
-		gap = s.numNodes
+// WriteCutSets writes the node numbers of the source set of the minimum
+// s-t cut to 'srcW' and those of the sink set to 'sinkW', one "n <node>"
+// line per node. The two sets partition the nodes: every node is written
+// to exactly one of the writers.
+func (s *Session) WriteCutSets(srcW, sinkW io.Writer) error {
+	if !s.solved {
+		return ErrNoSolution
+	}
+
+	set := s.sourceSet()
+	var err error
+	for i := uint(0); i < s.numNodes; i++ {
+		w := sinkW
+		if set[i] {
+			w = srcW
+		}
+		if _, err = w.Write([]byte(fmt.Sprintf("n %d\n", s.adjacencyList[i].number))); err != nil {
+			return err
+		}
 	}
 
+	return nil
+}
+
+// Cut returns the node numbers in the source set of the minimum s-t cut.
+// If the graph has more than one minimum cut, the source set is the one
+// found by the solver's node labels. With Context.SinkMinimalCut it is
+// instead the cut closest to the sink - all nodes that can't reach the
+// sink in the residual graph - which doesn't depend on how the solver got
+// there. Both cuts have the same capacity, the max flow value.
+func (s *Session) Cut() []uint {
+	set := s.sourceSet()
 	result := make([]uint, 0, s.numNodes)
 	for i := uint(0); i < s.numNodes; i++ {
-		if s.adjacencyList[i].label >= gap {
+		if set[i] && s.inputNode(i+1) {
 			result = append(result, s.adjacencyList[i].number)
 		}
 	}
 	return result
 }
 
-// static void
-// displayFlow (void)
-// C_source uses "a SRC DST FLOW" format; however, the examples we have,
-// e.g., http://lpsolve.sourceforge.net/5.5/DIMACS_asn.htm, use
-// "f SRC DST FLOW" format.  Here we use the latter, since we can
-// then use the examples as test cases.
-func (s *Session) displayFlow(w io.Writer) error {
-	var err error
-	for i := uint(0); i < s.numArcs; i++ {
-		if _, err = w.Write([]byte(fmt.Sprintf("f %d %d %d\n",
-			s.arcList[i].from.number,
-			s.arcList[i].to.number,
-			s.arcList[i].flow))); err != nil {
-			return err
+// SourceSet returns the node numbers in the source set of the minimum cut:
+// the nodes that the DisplayCut output lists, whether or not DisplayCut is
+// set. It is Cut, but returns nil if the Session has not processed any data.
+func (s *Session) SourceSet() []uint {
+	if !s.solved {
+		return nil
+	}
+	return s.Cut()
+}
+
+// SinkSet returns the node numbers in the sink set of the minimum cut - all
+// nodes not in SourceSet. It returns nil if the Session has not processed
+// any data.
+func (s *Session) SinkSet() []uint {
+	if !s.solved {
+		return nil
+	}
+
+	set := s.sourceSet()
+	result := make([]uint, 0, s.numNodes)
+	for i := uint(0); i < s.numNodes; i++ {
+		if !set[i] && s.inputNode(i+1) {
+			result = append(result, s.adjacencyList[i].number)
 		}
 	}
+	return result
+}
 
-	return nil
+// MaxCutArcCapacity returns the arc, with its flow, of the largest capacity
+// among the arcs from the source set to the sink set of the minimum cut. If
+// several arcs have the largest capacity any one of them is returned; it is
+// currently the first in the order of Flows. It returns ErrNoSolution if the
+// Session has not processed any data and an error if the max flow is 0.
+func (s *Session) MaxCutArcCapacity() (A, error) {
+	return s.cutArc(func(a, b *arc) bool { return a.capacity > b.capacity })
 }
 
-// ReadDimacsFile implements readDimacsFile of C source code.
-func (s *Session) readDimacsFile(r io.Reader) error {
-	sessionInitializer := NewSessionInitializer(s)
+// NarrowestCutArc returns the arc, with its flow, of the smallest capacity
+// among the arcs from the source set to the sink set of the minimum cut -
+// the weakest link of the cut. If several arcs have the smallest capacity
+// any one of them is returned; it is currently the first in the order of
+// Flows. It returns ErrNoSolution if the Session has not processed any data
+// and an error if the max flow is 0.
+func (s *Session) NarrowestCutArc() (A, error) {
+	return s.cutArc(func(a, b *arc) bool { return a.capacity < b.capacity })
+}
 
-	var i, numLines, from, to uint
-	var capacity int
-	var ch1 string
+// CutArcFraction returns the fraction of the arcs that go from the source
+// set to the sink set of the minimum cut: a small fraction means the flow is
+// limited by a few critical arcs, a large one that the bottleneck is diffuse.
+// It returns 0 if the Session has not processed any data.
+func (s *Session) CutArcFraction() float64 {
+	if !s.solved || s.numArcs == 0 {
+		return 0
+	}
 
-	buf := bufio.NewReader(r)
-	var atEOF bool
-	var n uint64
-	var haveSource, haveSink bool
-	for {
-		if atEOF {
-			break
+	return float64(len(s.cutArcs())) / float64(s.numArcs)
+}
+
+// CutArcs returns the arcs from the source set to the sink set of the
+// minimum cut, with their capacity and flow, in the order of Flows. Without
+// mandatory arcs their capacities sum to the max flow. It returns nil if the
+// Session has not processed any data.
+func (s *Session) CutArcs() []A {
+	if !s.solved {
+		return nil
+	}
+
+	cut := s.cutArcs()
+	arcs := make([]A, len(cut))
+	for i, a := range cut {
+		arcs[i] = A{From: a.from.number, To: a.to.number, Capacity: a.capacity, Flow: a.flow}
+	}
+	return arcs
+}
+
+// cutArcs returns the arcs of arcList from the source set to the sink set
+// of the minimum cut.
+func (s *Session) cutArcs() []*arc {
+	set := s.sourceSet()
+	var cut []*arc
+	for _, a := range s.arcList[:s.numArcs] {
+		if set[a.from.number-1] && !set[a.to.number-1] {
+			cut = append(cut, a)
 		}
+	}
+	return cut
+}
 
-		line, err := buf.ReadBytes('\n')
-		if err != nil && err != io.EOF {
-			return err
-		} else if err == io.EOF {
-			if len(bytes.TrimSpace(line)) == 0 {
-				break // nothing more to process
-			}
-			// ... at EOF with data but no '\n' line termination.
-			// While not necessary for os.Stdin; it can happen in a file.
-			atEOF = true
-		} else {
-			// Strip off EOL and white space
-			line = bytes.TrimSpace(line[:len(line)-1])
-			if len(line) == 0 {
-				continue // skip empty lines
-			}
+// cutArc returns the first arc of the minimum cut that no other is 'better'
+// than.
+func (s *Session) cutArc(better func(a, b *arc) bool) (A, error) {
+	if !s.solved {
+		return A{}, ErrNoSolution
+	}
+	if s.maxFlow == 0 {
+		return A{}, errors.New("max flow is 0 - no cut arc carries flow")
+	}
+
+	var best *arc
+	for _, a := range s.cutArcs() {
+		if best == nil || better(a, best) {
+			best = a
 		}
-		numLines++
+	}
+	return A{From: best.from.number, To: best.to.number, Capacity: best.capacity, Flow: best.flow}, nil
+}
 
-		/*
-		   cat dimacsMaxf.txt
-		   p max 6 8
-		   n 1 s
-		   n 6 t
-		   a 1 2 5
-		   a 1 3 15
-		   a 2 4 5
-		   a 2 5 5
-		   a 3 4 5
-		   a 3 5 5
-		   a 4 6 15
-		   a 5 6 5
-		*/
-		switch line[0] {
-		case 'p':
-			vals := strings.Fields(string(line))
-			if len(vals) != 4 {
-				return fmt.Errorf("p entry doesn't have 3 values, has: %d", len(vals))
-			}
-			n, err = strconv.ParseUint(vals[2], 10, 64)
-			if err != nil {
-				return err
-			}
-			numNodes := uint(n)
-			n, err = strconv.ParseUint(vals[3], 10, 64)
-			if err != nil {
-				return err
-			}
-			numArcs := uint(n)
+// FlowBalance returns the net flow - outflow less inflow - of every node
+// after a Run, keyed by node number. For a feasible solution it is the
+// max flow value at the source, its negative at the sink, and 0 at all
+// other nodes, so it can be used to audit the solution. It returns nil
+// if the Session has not processed any data.
+func (s *Session) FlowBalance() map[uint]int {
+	if !s.solved {
+		return nil
+	}
 
-			sessionInitializer.Init(numNodes, numArcs)
-		case 'a':
-			vals := strings.Fields(string(line))
-			if len(vals) != 4 {
-				return fmt.Errorf("a entry doesn't have 3 values, has: %d", len(vals))
-			}
-			n, err = strconv.ParseUint(vals[1], 10, 64)
-			if err != nil {
-				return err
+	excess := s.nodeExcess()
+	balance := make(map[uint]int, len(excess))
+	for i, v := range excess {
+		balance[uint(i+1)] = -v
+	}
+	return balance
+}
+
+// Flows returns the flow on each arc after a Run. The arcs are in the order
+// of the "f" lines of Run: the order in which the solver stores them, which
+// for a given input is always the same but is not the input order. It
+// returns nil if the Session has not processed any data.
+func (s *Session) Flows() []A {
+	if !s.solved {
+		return nil
+	}
+
+	flows := make([]A, 0, s.numArcs)
+	for _, a := range s.arcList[:s.numArcs] {
+		if s.inputArc(a) {
+			flows = append(flows, A{From: a.from.number, To: a.to.number, Capacity: a.capacity, Flow: a.flow})
+		}
+	}
+	return flows
+}
+
+// MaxFlow returns the max flow value - the capacity of the minimum cut -
+// of the last Run. It returns ErrNoSolution if no Run has completed.
+func (s *Session) MaxFlow() (uint, error) {
+	if !s.solved {
+		return 0, ErrNoSolution
+	}
+	return uint(s.maxFlow), nil
+}
+
+// NumNodes returns the number of nodes of the loaded graph, including a
+// super-source or super-sink added for Context.MultiTerminal.
+func (s *Session) NumNodes() uint {
+	return s.numNodes
+}
+
+// NumArcs returns the number of arcs of the loaded graph, after any merging
+// of parallel arcs; with Context.Undirected it is two per edge.
+func (s *Session) NumArcs() uint {
+	return s.numArcs
+}
+
+// Source returns the source node of the loaded graph.
+func (s *Session) Source() uint {
+	return s.source
+}
+
+// Sink returns the sink node of the loaded graph.
+func (s *Session) Sink() uint {
+	return s.sink
+}
+
+// AssertOptimal checks the flows of the last Run again and returns an error
+// describing every capacity, minimum flow or flow balance violation and any
+// difference between the flow into the sink and the capacity of the minimum
+// cut. It returns nil for a feasible, optimal solution, so it can be used as
+// a one line assertion in tests. It returns ErrNoSolution if no Run has
+// completed.
+func (s *Session) AssertOptimal() error {
+	if !s.solved {
+		return ErrNoSolution
+	}
+
+	excess := s.nodeExcess()
+	problems := s.violations(excess)
+	if flow, cut := excess[s.sink-1], s.minCut(); flow != cut {
+		problems = append(problems, fmt.Sprintf("Flow is not optimal - flow into the sink %d does not equal min cut %d", flow, cut))
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// LabelStats returns the number of distinct node labels after a Run and a
+// histogram of the number of nodes with each label. Labels are those at the
+// end of the flow phase: the source is at numNodes, nodes lifted by a gap
+// are at numNodes, and the nodes with labels at or above the gap are the
+// source set of the minimum cut; see Cut. The histogram counts every node,
+// unlike the solver's internal label counts, which leave out the source,
+// the sink and lifted nodes. With mandatory arcs the labels aren't used and
+// are all 0. It returns 0 and nil if the Session has not processed any data.
+func (s *Session) LabelStats() (distinct uint, histogram map[uint]uint) {
+	if !s.solved {
+		return 0, nil
+	}
+
+	histogram = make(map[uint]uint)
+	for i := uint(0); i < s.numNodes; i++ {
+		histogram[s.adjacencyList[i].label]++
+	}
+	return uint(len(histogram)), histogram
+}
+
+// SourceSaturation returns the max flow as a fraction of the total capacity
+// of the arcs out of the source. At 1.0 the source arcs are the bottleneck;
+// less means the constraint is elsewhere in the graph. It returns 0 if the
+// source has no out capacity or the Session has not processed any data.
+func (s *Session) SourceSaturation() float64 {
+	if !s.solved {
+		return 0
+	}
+
+	var capacity int
+	for i := uint(0); i < s.numArcs; i++ {
+		if s.arcList[i].from.number == s.source && s.arcList[i].to.number != s.source {
+			capacity += s.arcList[i].capacity
+		}
+	}
+	if capacity == 0 {
+		return 0
+	}
+	return float64(s.nodeExcess()[s.sink-1]) / float64(capacity)
+}
+
+// TopFlows returns the 'n' arcs carrying the most flow after a Run, sorted
+// by descending flow; arcs with equal flow are ordered by From and then To.
+// If 'n' exceeds the number of arcs, all arcs are returned. It returns nil if
+// the Session has not processed any data.
+func (s *Session) TopFlows(n int) []A {
+	if !s.solved || n <= 0 || s.numArcs == 0 {
+		return nil
+	}
+
+	top := s.Flows()
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].Flow != top[j].Flow {
+			return top[i].Flow > top[j].Flow
+		}
+		if top[i].From != top[j].From {
+			return top[i].From < top[j].From
+		}
+		return top[i].To < top[j].To
+	})
+	if n > len(top) {
+		n = len(top)
+	}
+	return top[:n]
+}
+
+// static void
+// displayFlow (void)
+// C_source uses "a SRC DST FLOW" format; however, the examples we have,
+// e.g., http://lpsolve.sourceforge.net/5.5/DIMACS_asn.htm, use
+// "f SRC DST FLOW" format.  Here we use the latter, since we can
+// then use the examples as test cases. With Context.UseArcPrefix the
+// records are "a SRC DST FLOW" as in C source. The records are in arcList
+// order or, with Context.SortOutput, by SRC and then DST.
+//
+// With Context.MaxFlowRecords only the first MaxFlowRecords arcs are
+// reported, followed by a comment noting how many were left out.
+func (s *Session) displayFlow(w io.Writer) error {
+	format := "f %d %d %d\n"
+	if s.ctx.UseArcPrefix {
+		format = "a %d %d %d\n"
+	}
+
+	arcs := s.arcList[:s.numArcs]
+	if s.inputNodes > 0 || s.ctx.SortOutput {
+		// leave arcList alone; skip a super-source's or super-sink's arcs
+		arcs = make([]*arc, 0, s.numArcs)
+		for _, a := range s.arcList[:s.numArcs] {
+			if s.inputArc(a) {
+				arcs = append(arcs, a)
 			}
-			from = uint(n)
-			n, err = strconv.ParseUint(vals[2], 10, 64)
-			if err != nil {
-				return err
+		}
+	}
+	if s.ctx.SortOutput {
+		sort.SliceStable(arcs, func(i, j int) bool {
+			if arcs[i].from.number != arcs[j].from.number {
+				return arcs[i].from.number < arcs[j].from.number
 			}
-			to = uint(n)
-			n, err = strconv.ParseUint(vals[3], 10, 64)
+			return arcs[i].to.number < arcs[j].to.number
+		})
+	}
+
+	var err error
+	for i := uint(0); i < uint(len(arcs)); i++ {
+		if s.ctx.MaxFlowRecords > 0 && i == uint(s.ctx.MaxFlowRecords) {
+			_, err = fmt.Fprintf(w, "c ... %d more flow records (truncated)\n", uint(len(arcs))-i)
+			return err
+		}
+		if _, err = w.Write([]byte(fmt.Sprintf(format,
+			arcs[i].from.number,
+			arcs[i].to.number,
+			arcs[i].flow))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defaultScanBufSize is the initial size of the input line buffer if
+// SetScanBufferSize isn't used; the buffer then grows for longer lines.
+const defaultScanBufSize = 64 * 1024
+
+// SetScanBufferSize sets the size of the buffer that DIMACS input is read
+// into a line at a time by the Run methods. It is allocated once per Run and
+// lines are not copied out of it, so a large buffer suits large files. It is
+// also the limit on the length of a line, with its EOL: a longer one, e.g., a
+// huge comment, is an error. By default the buffer starts at 64KiB and grows as
+// needed, to at most math.MaxInt32 bytes.
+// A size less than 1 restores the default.
+func (s *Session) SetScanBufferSize(n int) {
+	if n < 1 {
+		n = 0
+	}
+	s.scanBufSize = n
+}
+
+// newScanner returns a line Scanner for 'r' with the scan buffer size.
+func (s *Session) newScanner(r io.Reader) *bufio.Scanner {
+	size := defaultScanBufSize
+	if s.scanBufSize > 0 {
+		size = s.scanBufSize
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, size), s.scanLimit())
+	return scanner
+}
+
+// scanLimit returns the longest line, with its EOL, that newScanner reads:
+// the scan buffer size or, by default, math.MaxInt32.
+func (s *Session) scanLimit() int {
+	if s.scanBufSize > 0 {
+		return s.scanBufSize
+	}
+	return math.MaxInt32
+}
+
+// ReadDimacsFile implements readDimacsFile of C source code.
+// The 'p' line may also have the source and sink,
+// "p max <nodes> <arcs> <source> <sink>", in which case the
+// "n <node> s" and "n <node> t" lines must be omitted.
+func (s *Session) readDimacsFile(r io.Reader) error {
+	s.solved = false
+	sessionInitializer := NewSessionInitializer(s)
+
+	var i, numLines, arcLines, numArcs, from, to uint
+	var capacity int
+	var ch1 string
+
+	// the last line needn't end with '\n'; Scan returns it anyway
+	scanner := s.newScanner(r)
+	var err error
+	var haveProblem, haveSource, haveSink, skippedArcs bool
+	// with Context.MultiTerminal the graph is loaded once all lines are read
+	multi := s.ctx.MultiTerminal
+	var sources, sinks []terminal
+	var arcs []A
+	for scanner.Scan() {
+		// empty lines are skipped, but counted for error line numbers
+		numLines++
+		// Strip off EOL - "\n" or "\r\n" - and white space
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue // skip empty lines
+		}
+		if s.ctx.LinePreprocessor != nil {
+			line = bytes.TrimSpace(s.ctx.LinePreprocessor(line))
+			if len(line) == 0 {
+				continue
+			}
+		}
+
+		/*
+		   cat dimacsMaxf.txt
+		   p max 6 8
+		   n 1 s
+		   n 6 t
+		   a 1 2 5
+		   a 1 3 15
+		   a 2 4 5
+		   a 2 5 5
+		   a 3 4 5
+		   a 3 5 5
+		   a 4 6 15
+		   a 5 6 5
+		*/
+		switch line[0] {
+		case 'p':
+			if s.strict && haveProblem {
+				return inputError(numLines, ErrMultipleProblems, "second 'p' line")
+			}
+			vals, err := parseProblemLine(line)
 			if err != nil {
-				return err
+				return parseError(numLines, "p", line, err)
+			}
+
+			if s.validate {
+				s.reset()
+				s.numNodes = vals[0]
+			} else if multi {
+				s.reset()
+				s.numNodes = vals[0]
+				sources, sinks, arcs = nil, nil, make([]A, 0, vals[1])
+			} else {
+				sessionInitializer.Init(vals[0], vals[1])
 			}
-			capacity = int(n)
+			haveProblem = true
+			arcLines, numArcs = 0, vals[1]
 
+			if len(vals) == 4 {
+				sessionInitializer.SetSource(vals[2])
+				haveSource = true
+				sessionInitializer.SetSink(vals[3])
+				haveSink = true
+				if multi {
+					sources = append(sources, terminal{vals[2], -1})
+					sinks = append(sinks, terminal{vals[3], -1})
+				}
+			}
+		case 'a':
+			if !haveProblem {
+				if err = s.badLine(numLines, parseError(numLines, "order", line, errors.New("arc record before problem 'p' line"))); err != nil {
+					return err
+				}
+				continue
+			}
+			// arcList has room for the declared arcs only; more are an error
+			arcLines++
+			if arcLines > numArcs {
+				continue
+			}
+			if from, to, capacity, err = parseArcLine(line); err != nil {
+				if err = s.badLine(numLines, parseError(numLines, "a", line, err)); err != nil {
+					return err
+				}
+				skippedArcs = true
+				continue
+			}
+			if from < 1 || from > s.numNodes || to < 1 || to > s.numNodes {
+				if err = s.badLine(numLines, inputError(numLines, ErrNodeRange, "arc (%d, %d) has a node out of range 1-%d", from, to, s.numNodes)); err != nil {
+					return err
+				}
+				skippedArcs = true
+				continue
+			}
+			if s.strict && from == to {
+				return inputError(numLines, ErrSelfLoop, "arc (%d, %d) is a self-loop", from, to)
+			}
+
+			if s.validate {
+				continue
+			}
+			if multi {
+				arcs = append(arcs, A{From: from, To: to, Capacity: capacity})
+				if s.ctx.Undirected {
+					arcs = append(arcs, A{From: to, To: from, Capacity: capacity})
+				}
+				continue
+			}
 			sessionInitializer.AddArc(from, to, capacity)
 		case 'n':
-			vals := strings.Fields(string(line))
-			if len(vals) != 3 {
-				return fmt.Errorf("n entry doesn't have 2 values, has: %d", len(vals))
+			if !haveProblem {
+				if err = s.badLine(numLines, parseError(numLines, "order", line, errors.New("node record before problem 'p' line"))); err != nil {
+					return err
+				}
+				continue
+			}
+			termCap := -1
+			if multi {
+				i, ch1, termCap, err = parseTerminalLine(line)
+			} else {
+				i, ch1, err = parseNodeLine(line)
 			}
-			n, err = strconv.ParseUint(vals[1], 10, 64)
 			if err != nil {
-				return err
+				if err = s.badLine(numLines, parseError(numLines, "n", line, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			if i < 1 || i > s.numNodes {
+				if err = s.badLine(numLines, inputError(numLines, ErrNodeRange, "node %d out of range 1-%d", i, s.numNodes)); err != nil {
+					return err
+				}
+				continue
 			}
-			i = uint(n)
-			ch1 = vals[2]
 
 			if ch1 == "s" {
-				if haveSource {
-					return fmt.Errorf("muliple 's' n lines")
+				if haveSource && !multi {
+					return inputError(numLines, ErrTerminals, "multiple 's' n lines")
 				}
-				sessionInitializer.SetSource(i)
+				if !haveSource {
+					sessionInitializer.SetSource(i)
+				}
+				sources = append(sources, terminal{i, termCap})
 				haveSource = true
 			} else if ch1 == "t" {
-				if haveSink {
-					return fmt.Errorf("multiple 't' n lines")
+				if haveSink && !multi {
+					return inputError(numLines, ErrTerminals, "multiple 't' n lines")
+				}
+				if !haveSink {
+					sessionInitializer.SetSink(i)
 				}
-				sessionInitializer.SetSink(i)
+				sinks = append(sinks, terminal{i, termCap})
 				haveSink = true
 			} else {
-				return fmt.Errorf("unrecognized character %s on line %d", ch1, numLines)
+				if err = s.badLine(numLines, parseError(numLines, "n", line, fmt.Errorf("unrecognized character %s", ch1))); err != nil {
+					return err
+				}
 			}
 		case 'c':
 			continue // catches "comment" lines
 		default:
-			return fmt.Errorf("unknown data: %s", string(line))
+			if err = s.badLine(numLines, parseError(numLines, "unknown", line, fmt.Errorf("unknown data: %s", string(line)))); err != nil {
+				return err
+			}
 		}
 	}
 
-	sessionInitializer.Complete()
+	if err = scanner.Err(); err != nil {
+		if err == bufio.ErrTooLong {
+			return fmt.Errorf("line %d is longer than the scan buffer size %d", numLines+1, s.scanLimit())
+		}
+		return err
+	}
+
+	if !haveProblem {
+		return inputError(0, ErrSyntax, "no problem 'p' line")
+	}
+	// a source or sink of 0 would index adjacencyList out of range
+	if !haveSource {
+		return inputError(0, ErrTerminals, "no source - 'n <node> s' - line")
+	}
+	if !haveSink {
+		return inputError(0, ErrTerminals, "no sink - 'n <node> t' - line")
+	}
+	if s.source < 1 || s.source > s.numNodes || s.sink < 1 || s.sink > s.numNodes {
+		return inputError(0, ErrNodeRange, "source %d or sink %d out of range 1-%d", s.source, s.sink, s.numNodes)
+	}
+	if s.strict && s.source == s.sink {
+		return inputError(0, ErrTerminals, "source and sink are node %d", s.source)
+	}
+	if arcLines != numArcs {
+		return inputError(0, ErrArcCount, "declared %d arcs but found %d", numArcs, arcLines)
+	}
+	if s.validate {
+		return nil
+	}
+	if multi {
+		return s.loadMultiTerminal(sources, sinks, arcs)
+	}
+
+	// skipped 'a' lines leave unused arcs in arcList
+	if skippedArcs {
+		sessionInitializer.dropUnusedArcs()
+	}
+
+	return sessionInitializer.Complete()
+}
 
+// badLine handles a malformed line: with Context.SkipBadLines it is counted,
+// reported to Context.WarnWriter, if any, and nil is returned; otherwise
+// 'err' is returned.
+func (s *Session) badLine(line uint, err error) error {
+	if !s.ctx.SkipBadLines {
+		return err
+	}
+	s.stats.SkippedLines++
+	if s.ctx.WarnWriter != nil {
+		// ParseError and InputError messages start with the line number
+		msg := err.Error()
+		if !strings.HasPrefix(msg, fmt.Sprintf("line %d: ", line)) {
+			msg = fmt.Sprintf("line %d: %s", line, msg)
+		}
+		fmt.Fprintf(s.ctx.WarnWriter, "skipping %s\n", msg)
+	}
 	return nil
 }
 
+// parseProblemLine parses a "p max <nodes> <arcs>" line and returns the
+// number of nodes and arcs. Some dialects have source and sink on the 'p'
+// line, "p max <nodes> <arcs> <source> <sink>"; they are then returned too.
+func parseProblemLine(line []byte) ([]uint, error) {
+	vals := strings.Fields(string(line))
+	if len(vals) != 4 && len(vals) != 6 {
+		return nil, fmt.Errorf("p entry doesn't have 3 or 5 values, has: %d", len(vals))
+	}
+	nums := make([]uint, len(vals)-2)
+	for i, v := range vals[2:] {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = uint(n)
+	}
+	return nums, nil
+}
+
+// parseArcLine parses an "a <from> <to> <capacity>" line.
+func parseArcLine(line []byte) (from, to uint, capacity int, err error) {
+	vals := strings.Fields(string(line))
+	if len(vals) != 4 {
+		return 0, 0, 0, fmt.Errorf("a entry doesn't have 3 values, has: %d", len(vals))
+	}
+	var n uint64
+	if n, err = strconv.ParseUint(vals[1], 10, 64); err != nil {
+		return 0, 0, 0, err
+	}
+	from = uint(n)
+	if n, err = strconv.ParseUint(vals[2], 10, 64); err != nil {
+		return 0, 0, 0, err
+	}
+	to = uint(n)
+	if n, err = strconv.ParseUint(vals[3], 10, 64); err != nil {
+		return 0, 0, 0, err
+	}
+	return from, to, int(n), nil
+}
+
+// parseNodeLine parses an "n <node> <s|t>" line.
+func parseNodeLine(line []byte) (node uint, which string, err error) {
+	vals := strings.Fields(string(line))
+	if len(vals) != 3 {
+		return 0, "", fmt.Errorf("n entry doesn't have 2 values, has: %d", len(vals))
+	}
+	n, err := strconv.ParseUint(vals[1], 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	return uint(n), vals[2], nil
+}
+
 // SimpleInitialization implements simpleInitialization of C source code.
-func (s *Session) simpleInitialization() {
+// Saturating the source and sink arcs must not overflow a node's excess,
+// nor may the capacities of those arcs sum past the largest int; if they
+// would, it returns an error that wraps ErrCapacityOverflow and the graph
+// has to be loaded again.
+func (s *Session) simpleInitialization() error {
 	var i, size uint
 	var tempArc *arc
+	var r int
 
 	size = s.adjacencyList[s.source-1].numberOutOfTree
 	for i = 0; i < size; i++ {
 		tempArc = s.adjacencyList[s.source-1].outOfTree[i]
+		// flow is 0 unless set by s.SetInitialFlow
+		r = tempArc.capacity - tempArc.flow
+		if tempArc.to.excess > math.MaxInt-r {
+			return fmt.Errorf("excess overflow at node %d: %w", tempArc.to.number, ErrCapacityOverflow)
+		}
+		tempArc.to.excess += r
 		tempArc.flow = tempArc.capacity
-		tempArc.to.excess += tempArc.capacity
 	}
 
 	size = s.adjacencyList[s.sink-1].numberOutOfTree
 	for i = 0; i < size; i++ {
 		tempArc = s.adjacencyList[s.sink-1].outOfTree[i]
+		r = tempArc.capacity - tempArc.flow
+		if tempArc.from.excess < math.MinInt+r {
+			return fmt.Errorf("excess overflow at node %d: %w", tempArc.from.number, ErrCapacityOverflow)
+		}
+		tempArc.from.excess -= r
 		tempArc.flow = tempArc.capacity
-		tempArc.from.excess -= tempArc.capacity
+	}
+
+	// excess can collect at any node as the solver pushes it
+	var out, in int
+	for _, a := range s.arcList[:s.numArcs] {
+		if a.from.number == s.source {
+			if out > math.MaxInt-a.capacity {
+				return ErrCapacityOverflow
+			}
+			out += a.capacity
+		}
+		if a.to.number == s.sink {
+			if in > math.MaxInt-a.capacity {
+				return ErrCapacityOverflow
+			}
+			in += a.capacity
+		}
 	}
 
 	s.adjacencyList[s.source-1].excess = 0
@@ -993,37 +2035,53 @@ func (s *Session) simpleInitialization() {
 	s.adjacencyList[s.source-1].label = s.numNodes
 	s.adjacencyList[s.sink-1].label = 0
 	s.labelCount[0] = (s.numNodes - 2) - s.labelCount[1]
+	return nil
 }
 
 // FlowPhaseOne implements pseudoFlowPhase1 of C source code.
-func (s *Session) flowPhaseOne() {
+// The loop checks 'ctx' before taking the next strong root every
+// ctxCheckInterval roots and returns ctx.Err() if it is done; the
+// progress func, if any, is called there too.
+func (s *Session) flowPhaseOne(ctx context.Context) error {
 	var strongRoot *node
+	var roots uint
 
-	if s.ctx.LowestLabel {
-		strongRoot = s.getLowestStrongRoot()
-		for ; strongRoot != nil; strongRoot = s.getLowestStrongRoot() {
-			s.processRoot(strongRoot)
+	for {
+		if roots%ctxCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if s.progress != nil && roots > 0 {
+				s.progress(roots, s.numNodes)
+			}
 		}
-	} else {
-		strongRoot = s.getHighestStrongRoot()
-		for ; strongRoot != nil; strongRoot = s.getHighestStrongRoot() {
-			s.processRoot(strongRoot)
+
+		if s.ctx.LowestLabel {
+			strongRoot = s.getLowestStrongRoot()
+		} else {
+			strongRoot = s.getHighestStrongRoot()
+		}
+		if strongRoot == nil {
+			if s.progress != nil {
+				s.progress(roots, s.numNodes)
+			}
+			return nil
 		}
+		s.processRoot(strongRoot)
+		roots++
 	}
 }
 
+// ctxCheckInterval is the number of strong roots flowPhaseOne processes
+// between checks for cancellation and calls of the progress func.
+var ctxCheckInterval uint = 4096
+
 // static void
 // recoverFlow (const uint gap)
 // RecoverFlow implements recoverFlow of C source code.
 // It internalizes setting 'gap' value.
 func (s *Session) recoverFlow() {
-	// setting gap value is taken out of main() in C source code
-	var gap uint
-	if s.ctx.LowestLabel {
-		gap = s.lowestStrongLabel
-	} else {
-		gap = s.numNodes
-	}
+	gap := s.gap()
 
 	var i, j uint
 	iteration := uint(1)
@@ -1084,6 +2142,28 @@ func (s *Session) recoverFlow() {
 	}
 }
 
+// AlgorithmVariant returns the solver variant set by the Context as
+// "highest-label" or "lowest-label" and "lifo" or "fifo", e.g.,
+// "highest-label, lifo"; it is what the "c Runtime Configuration" lines of
+// a result report.
+func (s *Session) AlgorithmVariant() string {
+	label, buckets := s.variant()
+	return strings.ToLower(label) + "-label, " + strings.ToLower(buckets)
+}
+
+// variant returns the names of the strong root selection and the bucket
+// order of the Context for the result lines.
+func (s *Session) variant() (label, buckets string) {
+	label, buckets = "Highest", "LIFO"
+	if s.ctx.LowestLabel {
+		label = "Lowest"
+	}
+	if s.ctx.FifoBuckets {
+		buckets = "FIFO"
+	}
+	return label, buckets
+}
+
 // Result returns scan of arc/node results in Dimac syntax.
 //
 // Example for input file "maxflow.net":
@@ -1107,7 +2187,18 @@ func (s *Session) recoverFlow() {
 //	f 1 2 5
 //	f 1 3 10
 //	...
+//
+// With Context.CutSummaryOnly the result is only the header, if any, the
+// max flow and the sizes of the two sides of the minimum cut:
+//	c <header>
+//	s 15
+//	c source-set size: 2
+//	c sink-set size: 4
 func (s *Session) result(w io.Writer, header string) error {
+	if s.ctx.CutSummaryOnly {
+		return s.cutSummary(w, header)
+	}
+
 	// header and runtime config info
 	ret := [][]byte{
 		[]byte("c " + header + "\n"),
@@ -1128,28 +2219,17 @@ func (s *Session) result(w io.Writer, header string) error {
 		}
 	}
 
-	var line []byte
-	if s.ctx.LowestLabel {
-		line = []byte("c Lowest label pseudoflow algorithm\n")
-	} else {
-		line = []byte("c Highest label pseudoflow algorithm\n")
-	}
-	if _, err = w.Write(line); err != nil {
+	label, buckets := s.variant()
+	if _, err = w.Write([]byte("c " + label + " label pseudoflow algorithm\n")); err != nil {
 		return err
 	}
-
-	if s.ctx.FifoBuckets {
-		line = []byte("c Using FIFO buckets\n")
-	} else {
-		line = []byte("c Using LIFO buckets\n")
-	}
-	if _, err = w.Write(line); err != nil {
+	if _, err = w.Write([]byte("c Using " + buckets + " buckets\n")); err != nil {
 		return err
 	}
 
 	// add Solution
 	if err = s.checkOptimality(w); err != nil {
-		return nil
+		return err
 	}
 	if _, err = w.Write([]byte("c \n")); err != nil {
 		return err
@@ -1173,6 +2253,63 @@ func (s *Session) result(w io.Writer, header string) error {
 	return nil
 }
 
+// cutSummary writes the result of Context.CutSummaryOnly.
+func (s *Session) cutSummary(w io.Writer, header string) error {
+	var err error
+	if len(header) > 0 {
+		if _, err = w.Write([]byte("c " + header + "\n")); err != nil {
+			return err
+		}
+	}
+	var source, sink uint
+	for i, v := range s.sourceSet() {
+		if !s.inputNode(uint(i + 1)) {
+			continue
+		}
+		if v {
+			source++
+		} else {
+			sink++
+		}
+	}
+	_, err = fmt.Fprintf(w, "s %d\nc source-set size: %d\nc sink-set size: %d\n", s.maxFlow, source, sink)
+	return err
+}
+
+// IsConnected reports whether all nodes of the loaded graph are in one
+// component, ignoring arc direction. It can be called before solving;
+// disconnected nodes often mean a data error even if the sink can be
+// reached from the source. If the graph isn't connected, the number of
+// components is reported to Context.WarnWriter, if set.
+func (s *Session) IsConnected() bool {
+	// union-find with path halving
+	parent := make([]uint, s.numNodes)
+	for i := range parent {
+		parent[i] = uint(i)
+	}
+	find := func(n uint) uint {
+		for parent[n] != n {
+			parent[n] = parent[parent[n]]
+			n = parent[n]
+		}
+		return n
+	}
+
+	components := s.numNodes
+	for i := uint(0); i < s.numArcs; i++ {
+		from, to := find(s.arcList[i].from.number-1), find(s.arcList[i].to.number-1)
+		if from != to {
+			parent[from] = to
+			components--
+		}
+	}
+
+	if components > 1 && s.ctx.WarnWriter != nil {
+		fmt.Fprintf(s.ctx.WarnWriter, "graph is not connected: %d components\n", components)
+	}
+	return components <= 1
+}
+
 // ================ public functions =====================
 
 // Run takes an input file and returns the optimal flow if
@@ -1208,7 +2345,11 @@ func (s *Session) RunReader(r io.ReadCloser, header ...string) ([]string, error)
 		return nil, err
 	}
 
-	// extract the result
+	return resultLines(w)
+}
+
+// resultLines extracts the lines of the result in 'w'.
+func resultLines(w *bytes.Buffer) ([]string, error) {
 	ret := make([]string, 0)
 	for {
 		l, err := w.ReadBytes('\n')
@@ -1224,6 +2365,11 @@ func (s *Session) RunReader(r io.ReadCloser, header ...string) ([]string, error)
 	return ret, nil
 }
 
+// RunBytes is RunReader for input data held in 'b'.
+func (s *Session) RunBytes(b []byte, header ...string) ([]string, error) {
+	return s.RunReader(ioutil.NopCloser(bytes.NewReader(b)), header...)
+}
+
 // RunReadWriter supports large data set output to a predefined io.Writer.
 //	...
 //	s := NewSession(Context{})
@@ -1240,12 +2386,13 @@ func (s *Session) RunReader(r io.ReadCloser, header ...string) ([]string, error)
 func (s *Session) RunReadWriter(r io.ReadCloser, w io.Writer, header ...string) error {
 	// always reinitialize stats - might be making
 	// sucessive calls to Run
-	s.stats = statistics{}
+	s.stats = Stats{}
 
 	// implement C source main()
 	// load the data ...
 	s.times.start = time.Now()
 	if err := s.readDimacsFile(r); err != nil {
+		s.loaded = false
 		r.Close()
 		return err
 	}
@@ -1255,16 +2402,59 @@ func (s *Session) RunReadWriter(r io.ReadCloser, w io.Writer, header ...string)
 	return s.process(w, header...)
 }
 
+// RunReadWriterContext is RunReadWriter that stops solving and returns
+// ctx.Err() if 'ctx' is cancelled or times out. As for RunNAWriterContext,
+// cancellation is checked every few thousand strong roots of the flow phase;
+// reading the data and writing the result are not interrupted. On
+// cancellation the partial state is discarded: the Session has no graph and
+// no solution, and the input must be run again. Stats has the work done.
+func (s *Session) RunReadWriterContext(ctx context.Context, r io.ReadCloser, w io.Writer, header ...string) error {
+	s.stats = Stats{}
+
+	s.times.start = time.Now()
+	if err := s.readDimacsFile(r); err != nil {
+		s.loaded = false
+		r.Close()
+		return err
+	}
+	r.Close()
+
+	if err := s.processContext(ctx, w, header...); err != nil {
+		if s.interrupted {
+			s.reset()
+		}
+		return err
+	}
+	return nil
+}
+
+// RunWriter solves a graph that was loaded into the Session, but not solved,
+// by a SessionInitializer or LoadDumpJSON and writes the result to 'w' as
+// RunReadWriter does. It also resumes a solve that was cancelled, e.g., by
+// RunNAWriterContext, or restored by LoadState. A loaded graph can only be
+// solved once; it returns ErrNoGraph otherwise.
+func (s *Session) RunWriter(w io.Writer, header ...string) error {
+	if !s.loaded && !s.interrupted {
+		return ErrNoGraph
+	}
+	if !s.interrupted {
+		s.stats = Stats{}
+		s.times.start = time.Now()
+	}
+
+	return s.process(w, header...)
+}
+
 // process handles processing dimacs data. Split out to support s.RunNA.
 func (s *Session) process(w io.Writer, header ...string) error {
-	// find the solution ...
-	s.times.readfile = time.Now()
-	s.simpleInitialization()
-	s.times.initialize = time.Now()
-	s.flowPhaseOne()
-	s.times.flow = time.Now()
-	s.recoverFlow()
-	s.times.recflow = time.Now()
+	return s.processContext(context.Background(), w, header...)
+}
+
+// processContext is process that can be cancelled with 'ctx'.
+func (s *Session) processContext(ctx context.Context, w io.Writer, header ...string) error {
+	if err := s.solve(ctx); err != nil {
+		return err
+	}
 
 	// results might have custom header comment
 	var h string
@@ -1274,6 +2464,63 @@ func (s *Session) process(w io.Writer, header ...string) error {
 	return s.result(w, h)
 }
 
+// phaseOne runs flowPhaseOne and recovers the flow. If 'ctx' is done, the
+// Session is left interrupted so the solve can be resumed.
+func (s *Session) phaseOne(ctx context.Context) error {
+	if err := s.flowPhaseOne(ctx); err != nil {
+		s.interrupted = true
+		return err
+	}
+	s.times.flow = time.Now()
+	s.recoverFlow()
+	s.mergeSplitArcs()
+	s.times.recflow = time.Now()
+	return nil
+}
+
+// solve finds the solution for the loaded graph.
+func (s *Session) solve(ctx context.Context) error {
+	s.solved = false
+	s.loaded = false
+	if s.interrupted {
+		// resume flowPhaseOne
+		s.interrupted = false
+		if err := s.phaseOne(ctx); err != nil {
+			return err
+		}
+	} else if s.hasLowerBounds() {
+		s.times.readfile = time.Now()
+		if err := s.solveLowerBounds(ctx); err != nil {
+			return err
+		}
+	} else {
+		s.times.readfile = time.Now()
+		if s.initialFlow != nil {
+			if err := s.applyInitialFlow(); err != nil {
+				return err
+			}
+		}
+		if err := s.simpleInitialization(); err != nil {
+			return err
+		}
+		s.times.initialize = time.Now()
+		if err := s.phaseOne(ctx); err != nil {
+			return err
+		}
+	}
+
+	// in trusted pipelines a violation is a bug, not a comment;
+	// a rejected solution is not reported by the accessors
+	if s.ctx.StrictFeasibility {
+		if err := s.checkFeasibility(); err != nil {
+			return err
+		}
+	}
+	s.maxFlow = s.minCut()
+	s.solved = true
+	return nil
+}
+
 // RunJSON returns the results of Run as a JSON object. This
 // is useful if you want to return results to a JS app.
 func (s *Session) RunJSON(input string, header ...string) ([]byte, error) {
@@ -1296,32 +2543,202 @@ func (s *Session) RunReaderJSON(r io.ReadCloser, header ...string) ([]byte, erro
 	return json.Marshal(res)
 }
 
+// RunResult is RunReader but returns the solution as a Result, with
+// 'header' recorded in it, rather than as DIMACS text.
+func (s *Session) RunResult(r io.Reader, header string) (*Result, error) {
+	s.stats = Stats{}
+	s.times.start = time.Now()
+	if err := s.readDimacsFile(r); err != nil {
+		s.loaded = false
+		return nil, err
+	}
+	if err := s.solve(context.Background()); err != nil {
+		return nil, err
+	}
+	return s.newResult(header), nil
+}
+
+// Solution is the solution of Solve: the max flow, the flow on each arc in
+// the order of the "f" lines of Run, the source set and the arcs of the
+// minimum cut, and the stats of the solve.
+type Solution struct {
+	Header    string `json:"header,omitempty"`
+	MaxFlow   uint   `json:"maxflow"`
+	Flows     []A    `json:"flows"`
+	SourceSet []uint `json:"sourceSet"`
+	CutArcs   []A    `json:"cutArcs"`
+	Stats     Stats  `json:"stats"`
+}
+
+// Solve solves the DIMACS data read from 'r' once, as RunResult does, and
+// returns what Flows, SourceSet, CutArcs and Stats would then report.
+func (s *Session) Solve(r io.Reader, header ...string) (*Solution, error) {
+	var h string
+	if len(header) > 0 {
+		h = header[0]
+	}
+	res, err := s.RunResult(r, h)
+	if err != nil {
+		return nil, err
+	}
+	return &Solution{
+		Header:    res.Header,
+		MaxFlow:   uint(res.MaxFlow),
+		Flows:     res.Flows,
+		SourceSet: res.Cut,
+		CutArcs:   s.CutArcs(),
+		Stats:     res.Stats,
+	}, nil
+}
+
+// RunStream solves each of the DIMACS problems concatenated in 'r', e.g., a
+// benchmark archive, and returns their Solutions in order. A 'p' line starts
+// the next problem; blank and comment lines between problems are ignored.
+// If a problem can't be solved the error names it, counting from 1, and the
+// Solutions of the problems before it are returned with it.
+func (s *Session) RunStream(r io.Reader) ([]*Solution, error) {
+	var solutions []*Solution
+	var buf bytes.Buffer
+	var haveProblem bool
+	solve := func() error {
+		sol, err := s.Solve(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return fmt.Errorf("problem %d: %w", len(solutions)+1, err)
+		}
+		solutions = append(solutions, sol)
+		buf.Reset()
+		return nil
+	}
+
+	scanner := s.newScanner(r)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if p := bytes.TrimSpace(line); len(p) > 0 && p[0] == 'p' {
+			if haveProblem {
+				if err := solve(); err != nil {
+					return solutions, err
+				}
+			}
+			haveProblem = true
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return solutions, err
+	}
+	if haveProblem {
+		if err := solve(); err != nil {
+			return solutions, err
+		}
+	}
+	return solutions, nil
+}
+
+// CompareBucketStrategies solves the graph in 'r' with LIFO and then with
+// FIFO strong root buckets, using the default highest label algorithm, and
+// returns the Stats of each solve so the strategy to use for similar data
+// can be picked empirically. Fewer pushes, relabels and arc scans mean less
+// work; mergers and gaps describe how the solve went and aren't costs in
+// themselves. It returns an error if the two max flow values differ.
+func CompareBucketStrategies(r io.Reader) (fifoStats, lifoStats Stats, err error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fifoStats, lifoStats, err
+	}
+
+	var maxflow [2]int
+	for i, fifo := range []bool{false, true} {
+		s := NewSession(Context{FifoBuckets: fifo})
+		if err = s.RunReadWriter(ioutil.NopCloser(bytes.NewReader(data)), ioutil.Discard); err != nil {
+			return fifoStats, lifoStats, err
+		}
+		maxflow[i] = s.nodeExcess()[s.sink-1]
+		if fifo {
+			fifoStats = s.stats
+		} else {
+			lifoStats = s.stats
+		}
+	}
+	if maxflow[0] != maxflow[1] {
+		return fifoStats, lifoStats, fmt.Errorf("max flow differs: LIFO %d, FIFO %d", maxflow[0], maxflow[1])
+	}
+	return fifoStats, lifoStats, nil
+}
+
 // ======================== quicksort implementation
 
 // static void
 // quickSort (Arc **arr, const uint first, const uint last)
 // CLB: **Arc value is []*arc; slices manipulate the backing array
+// Unlike the C source it doesn't recurse: of the two partitions the larger
+// is pushed on a stack and the smaller is sorted next, so the stack holds
+// O(log n) ranges however the pivots fall. The arcs are ordered as by the
+// recursive version.
 func quickSort(arr []*arc, first, last uint) {
-	left, right := first, last
-	var swap *arc
+	var stack [][2]uint
 
-	// Bubble sort if 5 elements or less
-	if (right - left) <= 5 {
-		for i := right; i > left; i-- {
-			swap = nil
-			for j := left; j < i; j++ {
-				if arr[j].flow < arr[j+1].flow {
-					swap = arr[j]
-					arr[j] = arr[j+1]
-					arr[j+1] = swap
-				}
+	for {
+		if (last - first) <= 5 {
+			bubbleSort(arr, first, last)
+			if len(stack) == 0 {
+				return
+			}
+			first, last = stack[len(stack)-1][0], stack[len(stack)-1][1]
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		left := partition(arr, first, last)
+
+		lower, upper := left > first+1, left+1 < last
+		switch {
+		case lower && upper:
+			if left-first > last-left {
+				stack = append(stack, [2]uint{first, left - 1})
+				first = left + 1
+			} else {
+				stack = append(stack, [2]uint{left + 1, last})
+				last = left - 1
 			}
-			if swap != nil {
+		case lower:
+			last = left - 1
+		case upper:
+			first = left + 1
+		default:
+			if len(stack) == 0 {
 				return
 			}
+			first, last = stack[len(stack)-1][0], stack[len(stack)-1][1]
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// bubbleSort sorts arr[left:right+1] by decreasing flow; quickSort uses
+// it when right - left <= 5, as the C source does.
+func bubbleSort(arr []*arc, left, right uint) {
+	var swap *arc
+	for i := right; i > left; i-- {
+		swap = nil
+		for j := left; j < i; j++ {
+			if arr[j].flow < arr[j+1].flow {
+				swap = arr[j]
+				arr[j] = arr[j+1]
+				arr[j+1] = swap
+			}
+		}
+		if swap == nil {
+			return // no swaps, so sorted - as in C source
 		}
-		return
 	}
+}
+
+// partition partitions arr[first:last+1] about the median of three of its
+// first, middle and last flows and returns the index of the pivot.
+func partition(arr []*arc, first, last uint) uint {
+	left, right := first, last
+	var swap *arc
 
 	pivot := (first + last) / 2
 	x1 := arr[first].flow
@@ -1362,14 +2779,14 @@ func quickSort(arr []*arc, first, last uint) {
 		}
 	}
 
+	// the loop ends at left == right without comparing arr[left]; the C
+	// source swaps it with the pivot regardless, leaving arrays unsorted
+	if arr[left].flow < pivotval {
+		left--
+	}
 	swap = arr[first]
 	arr[first] = arr[left]
 	arr[left] = swap
 
-	if first < (left - 1) {
-		quickSort(arr, first, left-1)
-	}
-	if left+1 < last {
-		quickSort(arr, left+1, last)
-	}
+	return left
 }
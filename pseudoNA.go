@@ -5,10 +5,13 @@ package pseudo
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"strconv"
-	"strings"
+	"sort"
+	"time"
 )
 
 // N is the dimacs 'n' entry
@@ -17,22 +20,91 @@ type N struct {
 	Node string
 }
 
-// A is the dimacs 'a' entry
+// A is the dimacs 'a' entry. Capacity must be non-negative. Flow is not part
+// of the input; it is the flow on the arc in a solution or an initial flow
+// for s.SetInitialFlow.
 type A struct {
 	From     uint
 	To       uint
 	Capacity int
+	Flow     int
 }
 
 // RunNAWriter solves optimal flow given slices of 'n' and 'a' dimacs entries.
+// As with RunReadWriter the result is not buffered: each line is written to
+// 'w' as it is produced, so the output of a huge graph can be streamed; wrap
+// 'w' in a bufio.Writer to batch the writes.
 func (s *Session) RunNAWriter(numNodes, numArcs uint, nodes []N, arcs []A, w io.Writer, header ...string) error {
 	if err := s.loadNA(numNodes, numArcs, nodes, arcs); err != nil {
+		s.loaded = false
 		return err
 	}
 	return s.process(w, header...)
 }
 
+// RunNAWriterContext is RunNAWriter that stops solving and returns ctx.Err()
+// if 'ctx' is cancelled or times out. Cancellation is checked between strong
+// roots in the flow phase - every few thousand roots - so a solve stops soon
+// after 'ctx' is done but not immediately. Loading the data and writing the
+// result are not interrupted. After cancellation the Session has no
+// solution; RunWriter resumes the solve and SaveState can checkpoint it.
+func (s *Session) RunNAWriterContext(ctx context.Context, numNodes, numArcs uint, nodes []N, arcs []A, w io.Writer, header ...string) error {
+	if err := s.loadNA(numNodes, numArcs, nodes, arcs); err != nil {
+		s.loaded = false
+		return err
+	}
+	return s.processContext(ctx, w, header...)
+}
+
+// RunNAWriterFunc is RunNAWriter for a graph whose arcs, given as from and
+// to nodes, have capacities that are computed or looked up on demand rather
+// than held by the caller. 'capFn' is called once per arc, in the order of
+// 'arcs', as the graph is loaded; its result must not be negative. The graph
+// is loaded as RunNAWriter loads it.
+func (s *Session) RunNAWriterFunc(numNodes uint, nodes []N, arcs [][2]uint, capFn func(from, to uint) int, w io.Writer, header ...string) error {
+	s.times.start = time.Now()
+	a := make([]A, len(arcs))
+	for i, v := range arcs {
+		a[i] = A{From: v[0], To: v[1], Capacity: capFn(v[0], v[1])}
+	}
+	if err := s.loadNA(numNodes, uint(len(a)), nodes, a); err != nil {
+		s.loaded = false
+		return err
+	}
+	return s.process(w, header...)
+}
+
+// RunMatrix is RunNAWriter for a graph given as a square capacity matrix:
+// capacity[i][j] is the capacity of the arc from node i+1 to node j+1. There
+// is an arc for each non-zero entry off the diagonal, so the graph has
+// len(capacity) nodes and as many arcs as those entries.
+func (s *Session) RunMatrix(capacity [][]int, source, sink uint, w io.Writer, header ...string) error {
+	n := len(capacity)
+	var arcs []A
+	for i, row := range capacity {
+		if len(row) != n {
+			return fmt.Errorf("capacity matrix is not square: row %d has %d columns, want %d", i+1, len(row), n)
+		}
+		for j, v := range row {
+			if v != 0 && i != j {
+				arcs = append(arcs, A{From: uint(i + 1), To: uint(j + 1), Capacity: v})
+			}
+		}
+	}
+
+	return s.RunNAWriter(uint(n), uint(len(arcs)), []N{{source, "s"}, {sink, "t"}}, arcs, w, header...)
+}
+
 func (s *Session) loadNA(nn, na uint, n []N, a []A) error {
+	if s.ctx.MergeParallelArcs {
+		merged := mergeParallelArcs(a)
+		na -= uint(len(a) - len(merged))
+		a = merged
+	}
+
+	s.reset()
+	s.stats = Stats{}
+	s.loaded = true
 	s.numNodes, s.numArcs = nn, na
 
 	// allocate & initialize storage
@@ -47,36 +119,30 @@ func (s *Session) loadNA(nn, na uint, n []N, a []A) error {
 		s.adjacencyList[i] = s.newNode(uint(i + 1))
 	}
 	for i = 0; i < s.numArcs; i++ {
-		s.arcList[i] = &arc{direction: 1} // newArc(1)
+		s.arcList[i] = newArc(1)
 	}
 
 	// process N values
-	if len(n) != 2 {
-		return fmt.Errorf("want 2 N vals, have %d", len(n))
+	var err error
+	if s.source, s.sink, err = terminals(nn, n); err != nil {
+		return err
 	}
-	var haveSrc, haveSink bool
-	for _, v := range n {
-		if v.Node == "s" {
-			s.source = v.Val
-			haveSrc = true
-		} else if v.Node == "t" {
-			s.sink = v.Val
-			haveSink = true
-		} else {
-			return fmt.Errorf("unrecognized character %s in N.Node value", v.Node)
+
+	// process A values
+	for _, v := range a {
+		if v.Capacity < 0 {
+			return fmt.Errorf("negative capacity %d on arc (%d, %d)", v.Capacity, v.From, v.To)
+		}
+		if v.From < 1 || v.From > nn || v.To < 1 || v.To > nn {
+			return fmt.Errorf("arc (%d, %d) has a node out of range 1-%d", v.From, v.To, nn)
 		}
-	}
-	// check if there are 2 source or sink values
-	if haveSrc && !haveSink {
-		return fmt.Errorf("N slice does not include a sink - N.Node == t - value")
-	}
-	if !haveSrc && haveSink {
-		return fmt.Errorf("N slice does not include a source - N.Node == s - value")
 	}
 
-	// process A values
-	first := uint(0)
-	last := s.numArcs - 1
+	// with no arcs numArcs-1 would wrap around
+	var first, last uint
+	if s.numArcs > 0 {
+		last = s.numArcs - 1
+	}
 	for _, v := range a {
 		if (v.From+v.To)%2 != 0 {
 			s.arcList[first].from = s.adjacencyList[v.From-1]
@@ -122,19 +188,123 @@ func (s *Session) loadNA(nn, na uint, n []N, a []A) error {
 	return nil
 }
 
+// terminals returns the source and sink of the N values 'n' of a graph of
+// 'numNodes' nodes.
+func terminals(numNodes uint, n []N) (source, sink uint, err error) {
+	if len(n) != 2 {
+		return 0, 0, fmt.Errorf("want 2 N vals, have %d", len(n))
+	}
+	var haveSrc, haveSink bool
+	for _, v := range n {
+		if v.Node == "s" {
+			source = v.Val
+			haveSrc = true
+		} else if v.Node == "t" {
+			sink = v.Val
+			haveSink = true
+		} else {
+			return 0, 0, fmt.Errorf("unrecognized character %s in N.Node value", v.Node)
+		}
+	}
+	// check if there are 2 source or sink values
+	if haveSrc && !haveSink {
+		return 0, 0, fmt.Errorf("N slice does not include a sink - N.Node == t - value")
+	}
+	if !haveSrc && haveSink {
+		return 0, 0, fmt.Errorf("N slice does not include a source - N.Node == s - value")
+	}
+
+	if source < 1 || source > numNodes || sink < 1 || sink > numNodes {
+		return 0, 0, fmt.Errorf("source %d or sink %d out of range 1-%d", source, sink, numNodes)
+	}
+	return source, sink, nil
+}
+
+// mergeParallelArcs returns 'arcs' with the arcs that have the same From
+// and To replaced by the first of them, with the sum of their capacities.
+func mergeParallelArcs(arcs []A) []A {
+	merged := make([]A, 0, len(arcs))
+	index := make(map[[2]uint]int, len(arcs))
+	for _, v := range arcs {
+		k := [2]uint{v.From, v.To}
+		if i, ok := index[k]; ok {
+			merged[i].Capacity += v.Capacity
+			continue
+		}
+		index[k] = len(merged)
+		merged = append(merged, v)
+	}
+	return merged
+}
+
+// CompactNodeNumbers renumbers the nodes referenced by 'arcs' contiguously
+// from 1, preserving their relative order, so that node numbers with gaps -
+// e.g., 1, 2, 5, 9 - don't waste adjacencyList space or index past numNodes.
+// Optional 'nodes' - the source and sink N values - are included in the
+// renumbering so that a terminal with no incident arcs is still mapped.
+// It returns the renumbered arcs, the mapping from original to new node
+// numbers, and the number of distinct nodes. Use 'mapping' to translate
+// the source and sink N values before calling s.RunNAWriter and to translate
+// results back to the original numbering. A node that is in neither 'arcs'
+// nor 'nodes' is not in 'mapping'; use the "v, ok := mapping[id]" form,
+// since a miss returns 0, which is not a valid node number.
+func CompactNodeNumbers(arcs []A, nodes ...N) (compacted []A, mapping map[uint]uint, n uint) {
+	ids := make([]uint, 0, 2*len(arcs)+len(nodes))
+	seen := make(map[uint]bool)
+	add := func(id uint) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, v := range arcs {
+		add(v.From)
+		add(v.To)
+	}
+	for _, v := range nodes {
+		add(v.Val)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	mapping = make(map[uint]uint, len(ids))
+	for i, id := range ids {
+		mapping[id] = uint(i + 1)
+	}
+
+	compacted = make([]A, len(arcs))
+	for i, v := range arcs {
+		compacted[i] = v
+		compacted[i].From = mapping[v.From]
+		compacted[i].To = mapping[v.To]
+	}
+
+	return compacted, mapping, uint(len(ids))
+}
+
 // ParseDimacsReader generates input data for s.RunNAWriter. It is generally for tests.
+// As with s.Run the 'p' line may have the source and sink; they are returned as N values.
+// Gzipped input is detected by its magic bytes and decompressed.
 func ParseDimacsReader(r io.Reader) (uint, uint, []N, []A, error) {
 	var numNodes, numArcs uint
 	n := []N{}
 	a := []A{}
 
-	var i, from, to uint
-	var capacity int
-	var ch1 string
-
 	buf := bufio.NewReader(r)
-	var atEOF bool
-	var num uint64
+	gz, err := isGzip(buf)
+	if err != nil {
+		return numNodes, numArcs, n, a, err
+	}
+	if gz {
+		zr, err := gzip.NewReader(buf)
+		if err != nil {
+			return numNodes, numArcs, n, a, fmt.Errorf("gzip input: %s", err)
+		}
+		defer zr.Close()
+		buf = bufio.NewReader(zr)
+	}
+
+	var atEOF, haveProblem bool
+	var numLines uint
 	for {
 		if atEOF {
 			break
@@ -142,17 +312,24 @@ func ParseDimacsReader(r io.Reader) (uint, uint, []N, []A, error) {
 
 		line, err := buf.ReadBytes('\n')
 		if err != nil && err != io.EOF {
+			if gz {
+				err = fmt.Errorf("gzip input: %s", err)
+			}
 			return numNodes, numArcs, n, a, err
 		} else if err == io.EOF {
-			if len(bytes.TrimSpace(line)) == 0 {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
 				break // nothing more to process
 			}
 			// ... at EOF with data but no '\n' line termination.
 			// While not necessary for os.Stdin; it can happen in a file.
 			atEOF = true
-		} else {
-			// Strip off EOL and white space
-			line = bytes.TrimSpace(line[:len(line)-1])
+		}
+		// empty lines are skipped, but counted for error line numbers
+		numLines++
+		if !atEOF {
+			// Strip off EOL - "\n" or "\r\n" - and white space
+			line = bytes.TrimSpace(line)
 			if len(line) == 0 {
 				continue // skip empty lines
 			}
@@ -160,59 +337,59 @@ func ParseDimacsReader(r io.Reader) (uint, uint, []N, []A, error) {
 
 		switch line[0] {
 		case 'p':
-			vals := strings.Fields(string(line))
-			if len(vals) != 4 {
-				return numNodes, numArcs, n, a, fmt.Errorf("p entry doesn't have 3 values, has: %d", len(vals))
-			}
-			num, err = strconv.ParseUint(vals[2], 10, 64)
+			haveProblem = true
+			vals, err := parseProblemLine(line)
 			if err != nil {
-				return numNodes, numArcs, n, a, err
+				return numNodes, numArcs, n, a, parseError(numLines, "p", line, err)
 			}
-			numNodes = uint(num)
-			num, err = strconv.ParseUint(vals[3], 10, 64)
-			if err != nil {
-				return numNodes, numArcs, n, a, err
+			numNodes, numArcs = vals[0], vals[1]
+
+			// source and sink on the 'p' line
+			if len(vals) == 4 {
+				n = append(n, N{vals[2], "s"}, N{vals[3], "t"})
 			}
-			numArcs = uint(num)
 		case 'a':
-			vals := strings.Fields(string(line))
-			if len(vals) != 4 {
-				return numNodes, numArcs, n, a, fmt.Errorf("a entry doesn't have 3 values, has: %d", len(vals))
-			}
-			num, err = strconv.ParseUint(vals[1], 10, 64)
-			if err != nil {
-				return numNodes, numArcs, n, a, err
+			if !haveProblem {
+				return numNodes, numArcs, n, a, parseError(numLines, "order", line, errors.New("arc record before problem 'p' line"))
 			}
-			from = uint(num)
-			num, err = strconv.ParseUint(vals[2], 10, 64)
+			from, to, capacity, err := parseArcLine(line)
 			if err != nil {
-				return numNodes, numArcs, n, a, err
+				return numNodes, numArcs, n, a, parseError(numLines, "a", line, err)
 			}
-			to = uint(num)
-			num, err = strconv.ParseUint(vals[3], 10, 64)
-			if err != nil {
-				return numNodes, numArcs, n, a, err
-			}
-			capacity = int(num)
-			a = append(a, A{from, to, capacity})
+			a = append(a, A{From: from, To: to, Capacity: capacity})
 		case 'n':
-			vals := strings.Fields(string(line))
-			if len(vals) != 3 {
-				return numNodes, numArcs, n, a, fmt.Errorf("n entry doesn't have 2 values, has: %d", len(vals))
+			if !haveProblem {
+				return numNodes, numArcs, n, a, parseError(numLines, "order", line, errors.New("node record before problem 'p' line"))
 			}
-			num, err = strconv.ParseUint(vals[1], 10, 64)
+			i, ch1, err := parseNodeLine(line)
 			if err != nil {
-				return numNodes, numArcs, n, a, err
+				return numNodes, numArcs, n, a, parseError(numLines, "n", line, err)
 			}
-			i = uint(num)
-			ch1 = vals[2]
 			n = append(n, N{i, ch1})
 		case 'c':
 			continue // catches "comment" lines
 		default:
-			return numNodes, numArcs, n, a, fmt.Errorf("unknown data: %s", string(line))
+			return numNodes, numArcs, n, a, parseError(numLines, "unknown", line, fmt.Errorf("unknown data: %s", string(line)))
 		}
 	}
 
+	if uint(len(a)) != numArcs {
+		return numNodes, numArcs, n, a, inputError(0, ErrArcCount, "declared %d arcs but found %d", numArcs, len(a))
+	}
+
 	return numNodes, numArcs, n, a, nil
 }
+
+// ParseDimacsBytes is ParseDimacsReader for data held in 'b'.
+func ParseDimacsBytes(b []byte) (uint, uint, []N, []A, error) {
+	return ParseDimacsReader(bytes.NewReader(b))
+}
+
+// isGzip reports whether the buffered input starts with the gzip magic bytes.
+func isGzip(buf *bufio.Reader) (bool, error) {
+	b, err := buf.Peek(2)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return len(b) == 2 && b[0] == 0x1f && b[1] == 0x8b, nil
+}
@@ -2,9 +2,15 @@ package pseudo
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunNAWriter(t *testing.T) {
@@ -59,3 +65,317 @@ f 2 4 5
 f 1 3 10
 `
 
+func TestCompactNodeNumbers(t *testing.T) {
+	arcs := []A{
+		{From: 1, To: 2, Capacity: 5},
+		{From: 1, To: 5, Capacity: 15},
+		{From: 2, To: 9, Capacity: 5},
+		{From: 5, To: 9, Capacity: 5},
+	}
+	compacted, mapping, n := CompactNodeNumbers(arcs)
+	if n != 4 {
+		fmt.Println("n - want: 4 got:", n)
+		t.Fatal()
+	}
+	want := map[uint]uint{1: 1, 2: 2, 5: 3, 9: 4}
+	for k, v := range want {
+		if mapping[k] != v {
+			fmt.Println("mapping", k, "- want:", v, "got:", mapping[k])
+			t.Fatal()
+		}
+	}
+	check := []A{
+		{From: 1, To: 2, Capacity: 5},
+		{From: 1, To: 3, Capacity: 15},
+		{From: 2, To: 4, Capacity: 5},
+		{From: 3, To: 4, Capacity: 5},
+	}
+	for i, v := range compacted {
+		if v != check[i] {
+			fmt.Println(i, "- want:", check[i], "got:", v)
+			t.Fatal()
+		}
+	}
+
+	// solve the compacted graph
+	s := NewSession(Context{})
+	nodes := []N{{mapping[1], "s"}, {mapping[9], "t"}}
+	var buf bytes.Buffer
+	if err := s.RunNAWriter(n, uint(len(compacted)), nodes, compacted, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "s 10\n") {
+		fmt.Println("want: s 10 got:\n", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestCompactNodeNumbersIsolatedTerminal(t *testing.T) {
+	// sink 20 has no incident arcs
+	arcs := []A{{From: 3, To: 7, Capacity: 5}}
+	nodes := []N{{3, "s"}, {20, "t"}}
+	compacted, mapping, n := CompactNodeNumbers(arcs, nodes...)
+	if n != 3 {
+		fmt.Println("n - want: 3 got:", n)
+		t.Fatal()
+	}
+	sink, ok := mapping[20]
+	if !ok || sink != 3 {
+		fmt.Println("sink - want: 3 got:", sink, ok)
+		t.Fatal()
+	}
+
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err := s.RunNAWriter(n, uint(len(compacted)), []N{{mapping[3], "s"}, {sink, "t"}}, compacted, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "s 0\n") {
+		fmt.Println("want: s 0 got:\n", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestRunNAWriterContext(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err = s.RunNAWriterContext(ctx, numNodes, numArcs, n, a, &buf); err != context.Canceled {
+		fmt.Println("want:", context.Canceled, "got:", err)
+		t.Fatal()
+	}
+	if buf.Len() != 0 || s.FlowBalance() != nil {
+		fmt.Println("cancelled solve reported a result")
+		t.Fatal()
+	}
+
+	// not cancelled
+	if err = s.RunNAWriterContext(context.Background(), numNodes, numArcs, n, a, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != checkNAWriter {
+		fmt.Println("want:\n", checkNAWriter)
+		fmt.Println("got:\n", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestParseDimacsReaderGzip(t *testing.T) {
+	data, err := os.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	zw.Write(data)
+	zw.Close()
+
+	numNodes, numArcs, n, a, err := ParseDimacsReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzNodes, gzArcs, gzn, gza, err := ParseDimacsReader(bytes.NewReader(zbuf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gzNodes != numNodes || gzArcs != numArcs || !reflect.DeepEqual(gzn, n) || !reflect.DeepEqual(gza, a) {
+		fmt.Println("gzipped input parsed differently:", gzNodes, gzArcs, gzn, gza)
+		t.Fatal()
+	}
+
+	// truncated stream
+	_, _, _, _, err = ParseDimacsReader(bytes.NewReader(zbuf.Bytes()[:zbuf.Len()/2]))
+	if err == nil || !strings.HasPrefix(err.Error(), "gzip input: ") {
+		fmt.Println("want: gzip input error got:", err)
+		t.Fatal()
+	}
+}
+
+// lineWriter counts the lines written to it and the largest write.
+type lineWriter struct {
+	lines, maxWrite int
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lines += bytes.Count(p, []byte("\n"))
+	if len(p) > w.maxWrite {
+		w.maxWrite = len(p)
+	}
+	return len(p), nil
+}
+
+func TestRunNAWriterStreams(t *testing.T) {
+	fh, err := os.Open("_data/BVZ-tsukuba0.max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var w lineWriter
+	if err = NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, &w); err != nil {
+		t.Fatal(err)
+	}
+	// one "f" line per arc, none of it written in one piece
+	if w.lines < int(numArcs) || w.maxWrite > 100 {
+		fmt.Println("lines want: >=", numArcs, "got:", w.lines, "largest write:", w.maxWrite)
+		t.Fatal()
+	}
+}
+
+func TestParseDimacsBytes(t *testing.T) {
+	data, err := os.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	numNodes, numArcs, n, a, err := ParseDimacsBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != checkNAWriter {
+		fmt.Println("want:\n", checkNAWriter)
+		fmt.Println("got:\n", buf.String())
+		t.Fatal()
+	}
+
+	// as from readDimacsFile
+	if _, _, _, _, err = ParseDimacsBytes([]byte("p max 3 3\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n")); !errors.Is(err, ErrArcCount) {
+		fmt.Println("want:", ErrArcCount, "got:", err)
+		t.Fatal()
+	}
+}
+
+func TestRunNAWriterFunc(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// capacities computed from the node numbers
+	capacity := func(from, to uint) int { return int(from*to) % 7 }
+	arcs := make([][2]uint, numArcs)
+	for i, v := range a {
+		arcs[i] = [2]uint{v.From, v.To}
+		a[i].Capacity = capacity(v.From, v.To)
+	}
+	var want bytes.Buffer
+	if err = NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		var calls []A
+		capFn := func(from, to uint) int {
+			calls = append(calls, A{From: from, To: to})
+			return capacity(from, to)
+		}
+		var got bytes.Buffer
+		if err = NewSession(Context{}).RunNAWriterFunc(numNodes, n, arcs, capFn, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			fmt.Println("want:\n", want.String())
+			fmt.Println("got:\n", got.String())
+			t.Fatal()
+		}
+		// once per arc, in order
+		if len(calls) != len(arcs) {
+			fmt.Println("want:", len(arcs), "calls got:", len(calls))
+			t.Fatal()
+		}
+		for j, v := range calls {
+			if v.From != arcs[j][0] || v.To != arcs[j][1] {
+				fmt.Println("call", j, "want:", arcs[j], "got:", v)
+				t.Fatal()
+			}
+		}
+	}
+
+	// loaded as RunNAWriter loads it: Undirected doesn't apply
+	s := NewSession(Context{Undirected: true})
+	var got bytes.Buffer
+	if err = s.RunNAWriterFunc(numNodes, n, arcs, capacity, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		fmt.Println("Undirected - want:\n", want.String())
+		fmt.Println("got:\n", got.String())
+		t.Fatal()
+	}
+	if total := s.Timings().Total; total < 0 || total > time.Minute {
+		fmt.Println("want: the time of the solve got:", total)
+		t.Fatal()
+	}
+}
+
+func TestRunMatrix(t *testing.T) {
+	capacity := [][]int{
+		{0, 5, 15, 0, 0, 0},
+		{0, 0, 0, 5, 5, 0},
+		{0, 0, 0, 5, 5, 0},
+		{0, 0, 0, 0, 0, 15},
+		{0, 0, 0, 0, 0, 5},
+		{0, 0, 0, 0, 0, 9}, // the diagonal is ignored
+	}
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err := s.RunMatrix(capacity, 1, 6, &buf); err != nil {
+		t.Fatal(err)
+	}
+	// the arcs are loaded in row order rather than in the order of the
+	// sample file, so compare the flows as a set
+	want := map[A]bool{}
+	for _, v := range strings.Split(checkNAWriter, "\n") {
+		if strings.HasPrefix(v, "f ") {
+			want[parseFlowLine(v)] = true
+		}
+	}
+	var arcs int
+	for _, v := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(v, "f ") {
+			arcs++
+			if !want[parseFlowLine(v)] {
+				fmt.Println("unexpected flow:", v)
+				t.Fatal()
+			}
+		}
+	}
+	if arcs != 8 || !strings.Contains(buf.String(), "\ns 15\n") {
+		fmt.Println("want: 8 arcs and s 15 got:\n", buf.String())
+		t.Fatal()
+	}
+
+	if err := s.RunMatrix([][]int{{0, 1}, {1}}, 1, 2, &buf); err == nil {
+		fmt.Println("want: error for a matrix that isn't square")
+		t.Fatal()
+	}
+}
+
+func parseFlowLine(l string) A {
+	var a A
+	fmt.Sscanf(l, "f %d %d %d", &a.From, &a.To, &a.Flow)
+	return a
+}
@@ -0,0 +1,95 @@
+// pseudo_binary_test.go - binary DIMACS tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestBinaryDimacs(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = WriteBinaryDimacs(&buf, numNodes, numArcs, n, a); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != binaryHeader+int(numArcs)*binaryArc {
+		fmt.Println("want:", binaryHeader+int(numArcs)*binaryArc, "bytes got:", buf.Len())
+		t.Fatal()
+	}
+	data := buf.Bytes()
+
+	bNodes, bArcs, bn, ba, err := ParseBinaryDimacs(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bNodes != numNodes || bArcs != numArcs || !reflect.DeepEqual(ba, a) {
+		fmt.Println("round trip - want:", numNodes, numArcs, a, "got:", bNodes, bArcs, ba)
+		t.Fatal()
+	}
+	var want, got bytes.Buffer
+	NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, &want)
+	if err = NewSession(Context{}).RunNAWriter(bNodes, bArcs, bn, ba, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		fmt.Println("want:\n", want.String(), "got:\n", got.String())
+		t.Fatal()
+	}
+
+	if _, _, _, _, err = ParseBinaryDimacs(bytes.NewReader(data[:len(data)-1])); err == nil {
+		fmt.Println("want: truncated input error")
+		t.Fatal()
+	}
+	if _, _, _, _, err = ParseBinaryDimacs(bytes.NewReader([]byte("p max 6 8\n" + string(data)))); err == nil {
+		fmt.Println("want: bad magic error")
+		t.Fatal()
+	}
+}
+
+func BenchmarkParseDimacsText(b *testing.B) {
+	data, err := os.ReadFile("_data/BVZ-tsukuba0.max")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, err = ParseDimacsReader(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseBinaryDimacs(b *testing.B) {
+	data, err := os.ReadFile("_data/BVZ-tsukuba0.max")
+	if err != nil {
+		b.Fatal(err)
+	}
+	numNodes, numArcs, n, a, err := ParseDimacsReader(bytes.NewReader(data))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = WriteBinaryDimacs(&buf, numNodes, numArcs, n, a); err != nil {
+		b.Fatal(err)
+	}
+	data = buf.Bytes()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, _, err = ParseBinaryDimacs(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// pseudo_csv.go - CSV edge list input.
+
+package pseudo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RunCSV is RunNAWriter for a graph given as a CSV edge list, one
+// "from,to,capacity" row per arc; the number of arcs is the number of rows.
+// If the first field of the first row isn't a number the row is taken to be
+// a header and skipped. An error in a row is returned with its row number,
+// counting from 1 and including any header row.
+func (s *Session) RunCSV(r io.Reader, numNodes, source, sink uint, w io.Writer, header ...string) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // checked below, with the row number
+	cr.TrimLeadingSpace = true
+
+	var arcs []A
+	for row := 1; ; row++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if row == 1 && len(rec) > 0 {
+			if _, err = strconv.ParseUint(rec[0], 10, 64); err != nil {
+				continue // header row
+			}
+		}
+		if len(rec) != 3 {
+			return fmt.Errorf("CSV row %d doesn't have 3 fields, has: %d", row, len(rec))
+		}
+		var v [3]uint64
+		for i, f := range rec {
+			if v[i], err = strconv.ParseUint(f, 10, 64); err != nil {
+				return fmt.Errorf("CSV row %d: %s", row, err)
+			}
+		}
+		arcs = append(arcs, A{From: uint(v[0]), To: uint(v[1]), Capacity: int(v[2])})
+	}
+
+	return s.RunNAWriter(numNodes, uint(len(arcs)), []N{{source, "s"}, {sink, "t"}}, arcs, w, header...)
+}
@@ -0,0 +1,53 @@
+// pseudo_csv_test.go - RunCSV tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunCSV(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err = s.RunCSV(fh, 6, 1, 6, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != checkNAWriter {
+		fmt.Println("want:\n", checkNAWriter)
+		fmt.Println("got:\n", buf.String())
+		t.Fatal()
+	}
+
+	// no header row
+	buf.Reset()
+	if err = s.RunCSV(strings.NewReader("1,2,5\n2,3,4\n"), 3, 1, 3, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\ns 4\n") {
+		fmt.Println("want: s 4 got:\n", buf.String())
+		t.Fatal()
+	}
+
+	for _, v := range []struct {
+		data, want string
+	}{
+		{"from,to,capacity\n1,2,5\n2,3\n", "CSV row 3 doesn't have 3 fields, has: 2"},
+		{"1,2,5\n2,3,x\n", `CSV row 2: strconv.ParseUint: parsing "x": invalid syntax`},
+		{"1,2,5\n2,3,-4\n", `CSV row 2: strconv.ParseUint: parsing "-4": invalid syntax`},
+	} {
+		if err = s.RunCSV(strings.NewReader(v.data), 3, 1, 3, &buf); err == nil || err.Error() != v.want {
+			fmt.Println("want:", v.want, "got:", err)
+			t.Fatal()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// pseudo_disjoint.go - counting vertex-disjoint and edge-disjoint paths,
+// and max flows between other nodes of the loaded graph.
+
+package pseudo
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxVertexDisjointPaths returns the maximum number of source-sink paths of
+// the loaded graph that share no node other than 'source' and 'sink'. By
+// Menger's theorem it is also the fewest nodes whose removal disconnects
+// 'sink' from 'source'. Arc capacities are ignored and parallel arcs count
+// as one arc; 'source' and 'sink' need not be those of the input.
+//
+// The count is the max flow of a transformed graph that is solved with a
+// separate Session. Each node v is split into v-in, node v, and v-out,
+// node v+numNodes, joined by an arc of capacity 1, so at most one path can
+// pass through v; each arc (u, v) becomes an arc (u-out, v-in) of capacity
+// 1. The source and sink are not split. The Session's graph, solution and
+// stats are not changed.
+func (s *Session) MaxVertexDisjointPaths(source, sink uint) (int, error) {
+	if err := s.checkTerminals(source, sink); err != nil {
+		return 0, err
+	}
+
+	n := s.numNodes
+	out := func(v uint) uint {
+		if v == source || v == sink {
+			return v
+		}
+		return v + n
+	}
+
+	arcs := make([]A, 0, n+s.numArcs)
+	for v := uint(1); v <= n; v++ {
+		if v != source && v != sink {
+			arcs = append(arcs, A{From: v, To: v + n, Capacity: 1})
+		}
+	}
+	seen := make(map[[2]uint]bool)
+	for _, a := range s.arcList[:s.numArcs] {
+		k := [2]uint{a.from.number, a.to.number}
+		if k[0] == k[1] || seen[k] {
+			continue
+		}
+		seen[k] = true
+		arcs = append(arcs, A{From: out(k[0]), To: k[1], Capacity: 1})
+	}
+
+	return s.auxMaxFlow(2*n, source, sink, arcs)
+}
+
+// EdgeConnectivity returns the maximum number of source-sink paths of the
+// loaded graph that share no arc; by Menger's theorem it is also the fewest
+// arcs whose removal disconnects 'sink' from 'source'. It is the max flow of
+// the graph's structure with every arc capacity set to 1, so the original
+// capacities are ignored and parallel arcs are counted separately. 'source'
+// and 'sink' need not be those of the input. The max flow is solved with a
+// separate Session; the Session's graph, solution and stats are not changed.
+func (s *Session) EdgeConnectivity(source, sink uint) (int, error) {
+	if err := s.checkTerminals(source, sink); err != nil {
+		return 0, err
+	}
+
+	arcs := make([]A, 0, s.numArcs)
+	for _, a := range s.arcList[:s.numArcs] {
+		if a.from != a.to {
+			arcs = append(arcs, A{From: a.from.number, To: a.to.number, Capacity: 1})
+		}
+	}
+	return s.auxMaxFlow(s.numNodes, source, sink, arcs)
+}
+
+// MaxFlowToAllSinks returns the max flow of the loaded graph from 'source'
+// to each other node, keyed by node number; 'source' need not be that of
+// the input. The arcs are collected once and each max flow is solved with a
+// separate Session, so the cost is numNodes-1 solves. The Session's graph,
+// solution and stats are not changed.
+func (s *Session) MaxFlowToAllSinks(source uint) (map[uint]int, error) {
+	if s.numNodes == 0 {
+		return nil, ErrNoGraph
+	}
+	if source < 1 || source > s.numNodes {
+		return nil, fmt.Errorf("source %d out of range 1-%d", source, s.numNodes)
+	}
+
+	arcs := make([]A, 0, s.numArcs)
+	for _, a := range s.arcList[:s.numArcs] {
+		if a.from != a.to {
+			arcs = append(arcs, A{From: a.from.number, To: a.to.number, Capacity: a.capacity})
+		}
+	}
+	flows := make(map[uint]int, s.numNodes-1)
+	for sink := uint(1); sink <= s.numNodes; sink++ {
+		if sink == source {
+			continue
+		}
+		mf, err := s.auxMaxFlow(s.numNodes, source, sink, arcs)
+		if err != nil {
+			return nil, err
+		}
+		flows[sink] = mf
+	}
+	return flows, nil
+}
+
+// checkTerminals checks that there is a loaded graph and that 'source' and
+// 'sink' are different nodes of it.
+func (s *Session) checkTerminals(source, sink uint) error {
+	if s.numNodes == 0 {
+		return ErrNoGraph
+	}
+	if source < 1 || source > s.numNodes || sink < 1 || sink > s.numNodes || source == sink {
+		return fmt.Errorf("source %d and sink %d must be different nodes in 1-%d", source, sink, s.numNodes)
+	}
+	return nil
+}
+
+// auxMaxFlow returns the max flow of the graph 'arcs' of 'numNodes' nodes,
+// solved with a new Session.
+func (s *Session) auxMaxFlow(numNodes, source, sink uint, arcs []A) (int, error) {
+	aux := NewSession(Context{LowestLabel: s.ctx.LowestLabel, FifoBuckets: s.ctx.FifoBuckets})
+	if err := aux.loadNA(numNodes, uint(len(arcs)), []N{{source, "s"}, {sink, "t"}}, arcs); err != nil {
+		return 0, err
+	}
+	if err := aux.solve(context.Background()); err != nil {
+		return 0, err
+	}
+	return aux.maxFlow, nil
+}
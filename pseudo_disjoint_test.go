@@ -0,0 +1,179 @@
+// pseudo_disjoint_test.go - vertex-disjoint and edge-disjoint path tests.
+
+package pseudo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMaxVertexDisjointPaths(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.MaxVertexDisjointPaths(1, 2); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+
+	// two triangles joined at node 4: 2 arc-disjoint paths, 1 vertex-disjoint
+	data := `p max 7 8
+n 1 s
+n 7 t
+a 1 2 1
+a 1 3 1
+a 2 4 1
+a 3 4 1
+a 4 5 1
+a 4 6 1
+a 5 7 1
+a 6 7 1
+`
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if mf, _ := s.MaxFlow(); mf != 2 {
+		fmt.Println("max flow - want: 2 got:", mf)
+		t.Fatal()
+	}
+	n, err := s.MaxVertexDisjointPaths(1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		fmt.Println("want: 1 got:", n)
+		t.Fatal()
+	}
+	if mf, _ := s.MaxFlow(); mf != 2 {
+		fmt.Println("Session changed - max flow want: 2 got:", mf)
+		t.Fatal()
+	}
+	if _, err = s.MaxVertexDisjointPaths(1, 1); err == nil {
+		fmt.Println("want: error for source == sink")
+		t.Fatal()
+	}
+
+	// the 3-cube has vertex connectivity 3; nodes are 1 + the vertex bits
+	var b strings.Builder
+	b.WriteString("p max 8 24\nn 1 s\nn 8 t\n")
+	for v := 0; v < 8; v++ {
+		for bit := 1; bit < 8; bit <<= 1 {
+			fmt.Fprintf(&b, "a %d %d 10\n", v+1, v^bit+1)
+		}
+	}
+	if _, err = s.RunReader(ioutil.NopCloser(strings.NewReader(b.String()))); err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range [][2]uint{{1, 8}, {2, 7}, {1, 2}} {
+		if n, err = s.MaxVertexDisjointPaths(st[0], st[1]); err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			fmt.Println(st, "want: 3 got:", n)
+			t.Fatal()
+		}
+	}
+}
+
+func TestEdgeConnectivity(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.EdgeConnectivity(1, 2); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+
+	// max flow 15, but 2 arcs, 1->2 and 1->3, out of the source
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		source, sink uint
+		want         int
+	}{{1, 6, 2}, {1, 4, 2}, {2, 6, 2}, {4, 6, 1}, {6, 1, 0}} {
+		n, err := s.EdgeConnectivity(v.source, v.sink)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != v.want {
+			fmt.Println(v.source, v.sink, "want:", v.want, "got:", n)
+			t.Fatal()
+		}
+	}
+	if mf, _ := s.MaxFlow(); mf != 15 {
+		fmt.Println("Session changed - max flow want: 15 got:", mf)
+		t.Fatal()
+	}
+
+	// two triangles joined at node 4 - 2 edge-disjoint paths, unlike
+	// vertex-disjoint paths
+	data := `p max 7 8
+n 1 s
+n 7 t
+a 1 2 9
+a 1 3 9
+a 2 4 9
+a 3 4 9
+a 4 5 9
+a 4 6 9
+a 5 7 9
+a 6 7 9
+`
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := s.EdgeConnectivity(1, 7); err != nil || n != 2 {
+		fmt.Println("want: 2 got:", n, err)
+		t.Fatal()
+	}
+}
+
+func TestMaxFlowToAllSinks(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.MaxFlowToAllSinks(1); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	flows, err := s.MaxFlowToAllSinks(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flows) != 5 || flows[6] != 15 {
+		fmt.Println("want: 5 sinks and 15 to node 6 got:", flows)
+		t.Fatal()
+	}
+	if mf, _ := s.MaxFlow(); mf != 15 {
+		fmt.Println("Session changed - max flow want: 15 got:", mf)
+		t.Fatal()
+	}
+
+	// compare with solving each sink on its own
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	numNodes, numArcs, _, a, err := ParseDimacsReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sink := range []uint{2, 4, 5} {
+		single := NewSession(Context{})
+		if err = single.RunNAWriter(numNodes, numArcs, []N{{1, "s"}, {sink, "t"}}, a, ioutil.Discard); err != nil {
+			t.Fatal(err)
+		}
+		mf, _ := single.MaxFlow()
+		if uint(flows[sink]) != mf {
+			fmt.Println("sink", sink, "want:", mf, "got:", flows[sink])
+			t.Fatal()
+		}
+	}
+
+	if _, err = s.MaxFlowToAllSinks(7); err == nil {
+		fmt.Println("want: error for source out of range")
+		t.Fatal()
+	}
+}
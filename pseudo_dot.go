@@ -0,0 +1,54 @@
+// pseudo_dot.go - GraphViz output of a solution.
+
+package pseudo
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteDOT writes the solved flow network to 'w' as a GraphViz digraph. Each
+// arc is an edge labeled "flow/capacity", drawn bold if it is saturated, and
+// the nodes in the source set of the minimum cut, see Cut, are filled. Nodes
+// and arcs are in the order of Cut and Flows. It returns ErrNoSolution if
+// the Session has not processed any data.
+//
+//	digraph pseudo {
+//		1 [style=filled];
+//		2;
+//		...
+//		1 -> 2 [label="5/5", style=bold];
+//		2 -> 5 [label="0/5"];
+//		...
+//	}
+func (s *Session) WriteDOT(w io.Writer) error {
+	if !s.solved {
+		return ErrNoSolution
+	}
+
+	var err error
+	if _, err = io.WriteString(w, "digraph pseudo {\n"); err != nil {
+		return err
+	}
+	set := s.sourceSet()
+	for i := uint(0); i < s.numNodes; i++ {
+		style := ""
+		if set[i] {
+			style = " [style=filled]"
+		}
+		if _, err = fmt.Fprintf(w, "\t%d%s;\n", i+1, style); err != nil {
+			return err
+		}
+	}
+	for _, a := range s.arcList[:s.numArcs] {
+		style := ""
+		if a.flow == a.capacity {
+			style = ", style=bold"
+		}
+		if _, err = fmt.Fprintf(w, "\t%d -> %d [label=\"%d/%d\"%s];\n", a.from.number, a.to.number, a.flow, a.capacity, style); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
@@ -0,0 +1,51 @@
+// pseudo_dot_test.go - WriteDOT tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err := s.WriteDOT(&buf); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := buf.String()
+	if !strings.HasPrefix(dot, "digraph pseudo {\n") || !strings.HasSuffix(dot, "\n}\n") {
+		fmt.Println("not a digraph:\n", dot)
+		t.Fatal()
+	}
+	// the source set is 1 and 3
+	for _, want := range []string{
+		"\t1 [style=filled];\n",
+		"\t2;\n",
+		"\t3 [style=filled];\n",
+		"\t6;\n",
+		"\t1 -> 2 [label=\"5/5\", style=bold];\n",
+		"\t2 -> 5 [label=\"0/5\"];\n",
+		"\t1 -> 3 [label=\"10/15\"];\n",
+		"\t4 -> 6 [label=\"10/15\"];\n",
+	} {
+		if !strings.Contains(dot, want) {
+			fmt.Printf("want: %q got:\n%s", want, dot)
+			t.Fatal()
+		}
+	}
+	if n := strings.Count(dot, " -> "); n != 8 {
+		fmt.Println("want: 8 edges got:", n)
+		t.Fatal()
+	}
+}
@@ -0,0 +1,103 @@
+// pseudo_dump_test.go - DumpJSON, LoadDumpJSON and WriteDimacs tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestDumpJSON(t *testing.T) {
+	s := NewSession(Context{LowestLabel: true})
+	if _, err := s.DumpJSON(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	dump, err := s.DumpJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := LoadDumpJSON(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.ConfigJSON() != s.ConfigJSON() {
+		fmt.Println("want:", s.ConfigJSON(), "got:", s2.ConfigJSON())
+		t.Fatal()
+	}
+	var buf bytes.Buffer
+	if err = s2.RunWriter(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = s2.RunWriter(&buf); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+
+	// the re-solve reproduces the dump
+	dump2, err := s2.DumpJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dump, dump2) {
+		fmt.Println("want:\n", string(dump))
+		fmt.Println("got:\n", string(dump2))
+		t.Fatal()
+	}
+
+	if _, err = LoadDumpJSON([]byte(`{"graph":{"nodes":2,"source":1,"sink":2,"arcs":[{"from":1,"to":3,"capacity":1}]}}`)); err == nil {
+		fmt.Println("want: out of range error")
+		t.Fatal()
+	}
+}
+
+func TestWriteDimacs(t *testing.T) {
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err := s.WriteDimacs(&buf); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteDimacs(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s2 := NewSession(Context{})
+	if err := s2.RunReadWriter(ioutil.NopCloser(&buf), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	// same arcs in the same order, so the same solution
+	if !reflect.DeepEqual(s2.Flows(), s.Flows()) || s2.source != s.source || s2.sink != s.sink {
+		fmt.Println("want:", s.Flows(), "got:", s2.Flows())
+		t.Fatal()
+	}
+
+	// built with a SessionInitializer
+	si := NewSessionInitializer(s)
+	si.Init(3, 2)
+	si.SetSource(1)
+	si.SetSink(3)
+	si.AddArc(1, 2, 5)
+	si.AddArc(2, 3, 4)
+	if err := si.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := s.WriteDimacs(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "p max 3 2\nn 1 s\nn 3 t\na 1 2 5\na 2 3 4\n"; buf.String() != want {
+		fmt.Println("want:\n", want, "got:\n", buf.String())
+		t.Fatal()
+	}
+}
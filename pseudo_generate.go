@@ -0,0 +1,55 @@
+// pseudo_generate.go - random DIMACS instances for testing and benchmarks.
+
+package pseudo
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// GenerateRandomDimacs writes a random DIMACS max flow instance of 'numNodes'
+// nodes and 'numArcs' arcs to 'w'. The source is node 1 and the sink node
+// 'numNodes'. The first numNodes-1 arcs form a random tree from the source,
+// so every node, the sink included, can be reached from it; the others join
+// random pairs of different nodes. Capacities are in 1-'maxCap'. The same
+// 'seed' gives the same instance. It returns an error if there are fewer
+// than 2 nodes, too few arcs for the tree or 'maxCap' is less than 1.
+func GenerateRandomDimacs(w io.Writer, numNodes, numArcs uint, maxCap int, seed int64) error {
+	if numNodes < 2 {
+		return errors.New("need at least 2 nodes")
+	}
+	if numArcs < numNodes-1 {
+		return fmt.Errorf("need at least %d arcs to reach %d nodes from the source", numNodes-1, numNodes)
+	}
+	if maxCap < 1 {
+		return errors.New("maxCap must be at least 1")
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "c random instance, seed %d\np max %d %d\nn 1 s\nn %d t\n", seed, numNodes, numArcs, numNodes)
+
+	// the tree: each node in a random order is joined from one before it
+	order := make([]uint, numNodes-1)
+	for i := range order {
+		order[i] = uint(i + 2)
+	}
+	r.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	order = append([]uint{1}, order...)
+	for i := 1; i < len(order); i++ {
+		fmt.Fprintf(bw, "a %d %d %d\n", order[r.Intn(i)], order[i], 1+r.Intn(maxCap))
+	}
+
+	for i := numNodes - 1; i < numArcs; i++ {
+		from := uint(1 + r.Int63n(int64(numNodes)))
+		to := uint(1 + r.Int63n(int64(numNodes-1)))
+		if to >= from {
+			to++ // any node but 'from'
+		}
+		fmt.Fprintf(bw, "a %d %d %d\n", from, to, 1+r.Intn(maxCap))
+	}
+	return bw.Flush()
+}
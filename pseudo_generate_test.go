@@ -0,0 +1,89 @@
+// pseudo_generate_test.go - random instance tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestGenerateRandomDimacs(t *testing.T) {
+	for _, v := range []struct {
+		nodes, arcs uint
+		maxCap      int
+	}{
+		{2, 1, 1},
+		{10, 9, 5},
+		{100, 1000, 50},
+		{2000, 10000, 1000},
+	} {
+		var buf bytes.Buffer
+		if err := GenerateRandomDimacs(&buf, v.nodes, v.arcs, v.maxCap, 42); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if err := ValidateDimacs(bytes.NewReader(data)); err != nil {
+			fmt.Println(v, err)
+			t.Fatal()
+		}
+		numNodes, numArcs, n, a, err := ParseDimacsReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if numNodes != v.nodes || numArcs != v.arcs || len(a) != int(v.arcs) || len(n) != 2 {
+			fmt.Println(v, "got:", numNodes, numArcs, len(a), n)
+			t.Fatal()
+		}
+
+		// every node can be reached from the source
+		out := make(map[uint][]uint)
+		for _, arc := range a {
+			if arc.Capacity < 1 || arc.Capacity > v.maxCap {
+				fmt.Println(v, "capacity out of range:", arc)
+				t.Fatal()
+			}
+			out[arc.From] = append(out[arc.From], arc.To)
+		}
+		seen := map[uint]bool{1: true}
+		stack := []uint{1}
+		for len(stack) > 0 {
+			x := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, y := range out[x] {
+				if !seen[y] {
+					seen[y] = true
+					stack = append(stack, y)
+				}
+			}
+		}
+		if uint(len(seen)) != v.nodes {
+			fmt.Println(v, "reachable nodes:", len(seen))
+			t.Fatal()
+		}
+
+		// reproducible
+		var again bytes.Buffer
+		GenerateRandomDimacs(&again, v.nodes, v.arcs, v.maxCap, 42)
+		if !bytes.Equal(again.Bytes(), data) {
+			fmt.Println(v, "same seed gave a different instance")
+			t.Fatal()
+		}
+
+		s := NewSession(Context{})
+		if _, err = s.RunBytes(data); err != nil {
+			t.Fatal(err)
+		}
+		if mf, _ := s.MaxFlow(); mf == 0 {
+			fmt.Println(v, "want: a positive max flow")
+			t.Fatal()
+		}
+	}
+
+	for _, v := range [][3]int{{1, 0, 1}, {5, 3, 1}, {5, 4, 0}} {
+		if err := GenerateRandomDimacs(&bytes.Buffer{}, uint(v[0]), uint(v[1]), v[2], 1); err == nil {
+			fmt.Println(v, "want: error")
+			t.Fatal()
+		}
+	}
+}
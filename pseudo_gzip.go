@@ -0,0 +1,94 @@
+// pseudo_gzip.go - reading gzipped DIMACS input.
+
+package pseudo
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunGzip is Run for a gzipped input file. An error reading the gzip
+// stream, e.g., if the file is not gzipped or is truncated, is returned
+// with the prefix "gzip input: ".
+func (s *Session) RunGzip(input string, header ...string) ([]string, error) {
+	var fh *os.File
+	var err error
+	if strings.ToLower(input) == "stdin" {
+		fh = os.Stdin
+	} else {
+		fh, err = os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	zr, err := newGzipReadCloser(bufio.NewReader(fh), fh)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	if len(header) == 0 {
+		header = append(header, "Data: "+input)
+	}
+	return s.RunReader(zr, header...)
+}
+
+// RunReadWriterGzip is RunReadWriter for input that may be gzipped: if 'r'
+// starts with the gzip magic bytes it is decompressed, otherwise it is read
+// as is. An error reading the gzip stream is returned with the prefix
+// "gzip input: ".
+func (s *Session) RunReadWriterGzip(r io.ReadCloser, w io.Writer, header ...string) error {
+	buf := bufio.NewReader(r)
+	gz, err := isGzip(buf)
+	if err != nil {
+		r.Close()
+		return err
+	}
+	if !gz {
+		return s.RunReadWriter(bufReadCloser{buf, r}, w, header...)
+	}
+
+	zr, err := newGzipReadCloser(buf, r)
+	if err != nil {
+		r.Close()
+		return err
+	}
+	return s.RunReadWriter(zr, w, header...)
+}
+
+// bufReadCloser reads from a bufio.Reader and closes its underlying reader.
+type bufReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// gzipReadCloser decompresses a gzip stream and closes the reader of it.
+type gzipReadCloser struct {
+	zr *gzip.Reader
+	c  io.Closer
+}
+
+func newGzipReadCloser(r io.Reader, c io.Closer) (*gzipReadCloser, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("gzip input: %s", err)
+	}
+	return &gzipReadCloser{zr, c}, nil
+}
+
+func (g *gzipReadCloser) Read(p []byte) (int, error) {
+	n, err := g.zr.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("gzip input: %s", err)
+	}
+	return n, err
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.zr.Close()
+	return g.c.Close()
+}
@@ -0,0 +1,93 @@
+// pseudo_gzip_test.go - gzipped input tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunGzip(t *testing.T) {
+	data, err := ioutil.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	zw.Write(data)
+	zw.Close()
+
+	dir, err := ioutil.TempDir("", "pseudo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "dimacsMaxf.txt.gz")
+	if err = ioutil.WriteFile(file, zbuf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSession(Context{})
+	want, err := s.Run("_data/dimacsMaxf.txt", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.RunGzip(file, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+
+	// not gzipped
+	if _, err = s.RunGzip("_data/dimacsMaxf.txt"); err == nil || !strings.HasPrefix(err.Error(), "gzip input: ") {
+		fmt.Println("want: gzip input error got:", err)
+		t.Fatal()
+	}
+}
+
+func TestRunReadWriterGzip(t *testing.T) {
+	data, err := ioutil.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var zbuf bytes.Buffer
+	zw := gzip.NewWriter(&zbuf)
+	zw.Write(data)
+	zw.Close()
+
+	s := NewSession(Context{})
+	var want, got bytes.Buffer
+	if err = s.RunReadWriter(ioutil.NopCloser(bytes.NewReader(data)), &want); err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range [][]byte{zbuf.Bytes(), data} {
+		got.Reset()
+		if err = s.RunReadWriterGzip(ioutil.NopCloser(bytes.NewReader(in)), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			fmt.Println("want:\n", want.String())
+			fmt.Println("got:\n", got.String())
+			t.Fatal()
+		}
+	}
+
+	// corrupt stream
+	corrupt := append([]byte{}, zbuf.Bytes()...)
+	corrupt[len(corrupt)-5] ^= 0xff // the CRC-32
+	err = s.RunReadWriterGzip(ioutil.NopCloser(bytes.NewReader(corrupt)), &got)
+	if err == nil || !strings.HasPrefix(err.Error(), "gzip input: ") {
+		fmt.Println("want: gzip input error got:", err)
+		t.Fatal()
+	}
+}
@@ -1,9 +1,29 @@
 package pseudo
 
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
 type SessionInitializer struct {
 	session *Session
 	first   uint
 	last    uint
+	// deferred validation
+	deferred bool
+	pending  []pendingArc
+	// the first invalid AddArc, returned by Complete
+	err error
+	// arcs by (from, to) with Context.MergeParallelArcs
+	merge  map[[2]uint]*arc
+	merged bool
+}
+
+// pendingArc is an arc recorded by a deferred SessionInitializer.
+type pendingArc struct {
+	A
+	lower int
 }
 
 func NewSessionInitializer(session *Session) *SessionInitializer {
@@ -12,11 +32,28 @@ func NewSessionInitializer(session *Session) *SessionInitializer {
 	}
 }
 
+// NewDeferredSessionInitializer returns a SessionInitializer whose AddArc
+// only records the arcs. Complete then validates all arc endpoints together
+// and returns a single error listing every arc that references a node out
+// of range, rather than AddArc panicking on the first one. This is friendlier
+// for graphs built in batches.
+func NewDeferredSessionInitializer(session *Session) *SessionInitializer {
+	return &SessionInitializer{
+		session:  session,
+		deferred: true,
+	}
+}
+
 func (si *SessionInitializer) Init(numNodes, numArcs uint) {
 	s := si.session
+	s.reset()
+	if s.ctx.Undirected {
+		numArcs *= 2 // an arc for each direction of an edge
+	}
 
 	s.numNodes = numNodes
 	s.numArcs = numArcs
+	s.loaded = true
 
 	s.adjacencyList = make([]*node, numNodes)
 	s.strongRoots = make([]*root, numNodes)
@@ -29,10 +66,17 @@ func (si *SessionInitializer) Init(numNodes, numArcs uint) {
 		s.adjacencyList[i] = s.newNode(uint(i + 1))
 	}
 	for i = 0; i < numArcs; i++ {
-		s.arcList[i] = &arc{direction: 1} // newArc(1)
+		s.arcList[i] = newArc(1)
+	}
+	si.merge, si.merged = nil, false
+	if s.ctx.MergeParallelArcs {
+		si.merge = make(map[[2]uint]*arc)
 	}
 	si.first = 0
-	si.last = numArcs - 1
+	si.last = 0
+	if numArcs > 0 { // a graph with no arcs has flow 0
+		si.last = numArcs - 1
+	}
 }
 
 func (si *SessionInitializer) SetSource(source uint) {
@@ -43,9 +87,71 @@ func (si *SessionInitializer) SetSink(sink uint) {
 	si.session.sink = sink
 }
 
+// AddArc adds the arc (from, to). Capacities must be non-negative and, unless
+// the SessionInitializer is deferred, nodes must be in 1 through numNodes of
+// Init; an invalid arc is not added and Complete returns an error. With
+// Context.Undirected it adds the edge (from, to); see AddUndirectedArc.
 func (si *SessionInitializer) AddArc(from, to uint, capacity int) {
+	if si.session.ctx.Undirected {
+		si.AddUndirectedArc(from, to, capacity)
+		return
+	}
+	si.addInputArc(from, to, capacity)
+}
+
+// AddUndirectedArc adds the edge (u, v) as the arcs (u, v) and (v, u), each
+// with 'capacity', so flow of up to 'capacity' can go either way. It uses
+// two of the arcs of Init, but with Context.Undirected Init reserves two
+// arcs for each of its numArcs.
+func (si *SessionInitializer) AddUndirectedArc(u, v uint, capacity int) {
+	si.addInputArc(u, v, capacity)
+	si.addInputArc(v, u, capacity)
+}
+
+// addInputArc validates and adds, or with a deferred SessionInitializer
+// records, the arc (from, to).
+func (si *SessionInitializer) addInputArc(from, to uint, capacity int) {
+	if !si.checkArc(from, to, capacity) {
+		return
+	}
+	if si.deferred {
+		si.pending = append(si.pending, pendingArc{A: A{From: from, To: to, Capacity: capacity}})
+		return
+	}
+	si.addArc(from, to, capacity, 0)
+}
+
+// checkArc records an error for Complete if 'capacity' is negative or, if
+// the SessionInitializer isn't deferred, 'from' or 'to' is out of range. A
+// deferred SessionInitializer checks the nodes in Complete.
+func (si *SessionInitializer) checkArc(from, to uint, capacity int) bool {
+	var err error
+	n := si.session.numNodes
+	if capacity < 0 {
+		err = fmt.Errorf("negative capacity %d on arc (%d, %d)", capacity, from, to)
+	} else if !si.deferred && (from < 1 || from > n || to < 1 || to > n) {
+		err = fmt.Errorf("arc (%d, %d) has a node out of range 1-%d", from, to, n)
+	} else {
+		return true
+	}
+	if si.err == nil {
+		si.err = err
+	}
+	return false
+}
+
+func (si *SessionInitializer) addArc(from, to uint, capacity, lower int) {
 	s := si.session
 
+	if si.merge != nil {
+		if a, ok := si.merge[[2]uint{from, to}]; ok {
+			a.capacity += capacity
+			a.lower += lower
+			si.merged = true
+			return
+		}
+	}
+
 	// What's the point of loading arcList this way?
 	// 	(1+3)%2 = 0 --> arcList[first]
 	// 	(1+2)%2 = 1 --> arcList[last]
@@ -53,11 +159,19 @@ func (si *SessionInitializer) AddArc(from, to uint, capacity int) {
 		s.arcList[si.first].from = s.adjacencyList[from-1]
 		s.arcList[si.first].to = s.adjacencyList[to-1]
 		s.arcList[si.first].capacity = capacity
+		s.arcList[si.first].lower = lower
+		if si.merge != nil {
+			si.merge[[2]uint{from, to}] = s.arcList[si.first]
+		}
 		si.first++
 	} else {
 		s.arcList[si.last].from = s.adjacencyList[from-1]
 		s.arcList[si.last].to = s.adjacencyList[to-1]
 		s.arcList[si.last].capacity = capacity
+		s.arcList[si.last].lower = lower
+		if si.merge != nil {
+			si.merge[[2]uint{from, to}] = s.arcList[si.last]
+		}
 		si.last--
 	}
 
@@ -65,9 +179,71 @@ func (si *SessionInitializer) AddArc(from, to uint, capacity int) {
 	s.adjacencyList[to-1].numAdjacent++
 }
 
-func (si *SessionInitializer) Complete() {
+// loadOrder returns the arcs in an order that reproduces arcList if they
+// are loaded again. addArc stores odd (from+to) arcs from the front of
+// arcList and even ones from the back, so these are the odd arcs and then
+// the even ones, each in the order they were added.
+func (s *Session) loadOrder() []*arc {
+	arcs := make([]*arc, 0, s.numArcs)
+	for i := uint(0); i < s.numArcs; i++ {
+		if (s.arcList[i].from.number+s.arcList[i].to.number)%2 != 0 {
+			arcs = append(arcs, s.arcList[i])
+		}
+	}
+	for i := s.numArcs; i > 0; i-- {
+		if (s.arcList[i-1].from.number+s.arcList[i-1].to.number)%2 == 0 {
+			arcs = append(arcs, s.arcList[i-1])
+		}
+	}
+	return arcs
+}
+
+// dropUnusedArcs removes the arcList entries that no arc was added to,
+// e.g., because 'a' lines were skipped, and adjusts numArcs to match.
+func (si *SessionInitializer) dropUnusedArcs() {
+	s := si.session
+	if si.first > si.last || si.last >= s.numArcs {
+		return // all arcs used
+	}
+	s.arcList = append(s.arcList[:si.first], s.arcList[si.last+1:]...)
+	s.numArcs = uint(len(s.arcList))
+	si.last = si.first - 1
+}
+
+// Complete finishes loading the graph. It returns an error if an arc with a
+// negative capacity was added. With a deferred SessionInitializer it also
+// validates the recorded arcs and returns an error listing all arcs with an
+// endpoint out of range. The graph is not loaded in either case.
+func (si *SessionInitializer) Complete() error {
 	s := si.session
 
+	if si.err != nil {
+		s.loaded = false
+		return si.err
+	}
+
+	if si.deferred {
+		var bad []string
+		for i, v := range si.pending {
+			if v.From < 1 || v.From > s.numNodes || v.To < 1 || v.To > s.numNodes {
+				bad = append(bad, fmt.Sprintf("arc %d (%d, %d)", i+1, v.From, v.To))
+			}
+		}
+		if len(bad) > 0 {
+			s.loaded = false
+			return fmt.Errorf("arc endpoints out of range 1-%d: %s", s.numNodes, strings.Join(bad, ", "))
+		}
+		for _, v := range si.pending {
+			si.addArc(v.From, v.To, v.Capacity, v.lower)
+		}
+		si.pending = nil
+	}
+
+	// merged arcs leave unused arcs in arcList
+	if si.merged {
+		si.dropUnusedArcs()
+	}
+
 	for i := 0; i < int(s.numNodes); i++ {
 		s.adjacencyList[i].createOutOfTree()
 	}
@@ -90,4 +266,25 @@ func (si *SessionInitializer) Complete() {
 			}
 		}
 	}
+
+	return nil
+}
+
+// EstimateMemory returns the approximate number of bytes a Session allocates
+// for a graph of numNodes nodes and numArcs arcs: the node, arc and root
+// objects, the adjacencyList, arcList, strongRoots and labelCount slices,
+// and the per-node out-of-tree arc lists. It is an estimate for sizing a
+// server before accepting a job; it excludes transient allocations such as
+// input buffers, the results and Go runtime overhead.
+func EstimateMemory(numNodes, numArcs uint) uint64 {
+	ptr := uint64(unsafe.Sizeof(uintptr(0)))
+	perNode := uint64(unsafe.Sizeof(node{})) + // the node
+		uint64(unsafe.Sizeof(root{})) + // its strong root bucket
+		uint64(unsafe.Sizeof(uint(0))) + // labelCount entry
+		2*ptr // adjacencyList and strongRoots entries
+	perArc := uint64(unsafe.Sizeof(arc{})) + // the arc
+		ptr + // arcList entry
+		2*ptr // an outOfTree entry at each end
+
+	return uint64(numNodes)*perNode + uint64(numArcs)*perArc
 }
@@ -0,0 +1,280 @@
+// pseudo_initflow.go - warm starting a solve from a user supplied flow.
+
+package pseudo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// splitArc records an arc whose initial flow was strictly between 0 and its
+// capacity. The solver needs out-of-tree arcs to be either empty or
+// saturated, so 'orig' keeps the residual capacity and 'part' carries the
+// initial flow until mergeSplitArcs recombines them.
+type splitArc struct {
+	orig, part *arc
+}
+
+// SetInitialFlow seeds the next solve with 'flows' - the Flow values of
+// From/To arcs - so that pseudoflow refines a known flow rather than start
+// from scratch; e.g., when re-solving after a small change to a graph whose
+// flows were computed elsewhere. Capacity values are ignored.
+//
+// The seed must be a feasible flow for the graph that is then processed:
+// each flow must be in [0, capacity] of an arc with the same From and To,
+// flow must be conserved at every node other than source and sink, and
+// arcs into the source or out of the sink must not carry flow. Flows for
+// parallel arcs are matched, in order, to the arcs in the input. The
+// seed is validated and consumed by the next Run*; an invalid seed causes
+// that Run* to return an error.
+func (s *Session) SetInitialFlow(flows []A) error {
+	for _, v := range flows {
+		if v.Flow < 0 {
+			return fmt.Errorf("negative initial flow %d on arc (%d, %d)", v.Flow, v.From, v.To)
+		}
+	}
+	s.initialFlow = make([]A, len(flows))
+	copy(s.initialFlow, flows)
+	return nil
+}
+
+// applyInitialFlow validates s.initialFlow against the loaded graph and
+// seeds the arc flows and out-of-tree lists with it.
+func (s *Session) applyInitialFlow() error {
+	flows := s.initialFlow
+	s.initialFlow = nil
+
+	arcs := make(map[[2]uint][]*arc)
+	for _, a := range s.arcList {
+		k := [2]uint{a.from.number, a.to.number}
+		arcs[k] = append(arcs[k], a)
+	}
+
+	seeded := make([]*arc, 0, len(flows))
+	fail := func(err error) error {
+		for _, a := range seeded {
+			a.flow = 0
+		}
+		return err
+	}
+	for _, v := range flows {
+		k := [2]uint{v.From, v.To}
+		if len(arcs[k]) == 0 {
+			return fail(fmt.Errorf("initial flow on arc (%d, %d) which is not in the graph", v.From, v.To))
+		}
+		a := arcs[k][0]
+		arcs[k] = arcs[k][1:]
+		if v.Flow == 0 || (a.from.number == s.source && a.to.number == s.sink) {
+			continue // source-sink arcs are always saturated
+		}
+		if v.Flow > a.capacity {
+			return fail(fmt.Errorf("initial flow %d exceeds capacity %d on arc (%d, %d)", v.Flow, a.capacity, v.From, v.To))
+		}
+		if v.To == s.source || v.From == s.sink || v.From == v.To {
+			return fail(fmt.Errorf("initial flow on arc (%d, %d) which can't carry flow", v.From, v.To))
+		}
+		a.flow = v.Flow
+		seeded = append(seeded, a)
+	}
+
+	excess := s.nodeExcess()
+	for i, v := range excess {
+		if uint(i+1) != s.source && uint(i+1) != s.sink && v != 0 {
+			return fail(fmt.Errorf("initial flow is not balanced at node %d. Excess = %d", i+1, v))
+		}
+	}
+
+	// Arcs out of the source and into the sink are saturated by
+	// simpleInitialization. Other arcs are in their from node's out-of-tree
+	// list; the flow on them has to be pushable back from the to node.
+	for _, a := range seeded {
+		if a.from.number == s.source || a.to.number == s.sink {
+			continue
+		}
+		a.from.removeOutOfTreeNode(a)
+		if a.flow == a.capacity {
+			a.direction = 0
+			a.to.addOutOfTreeNode(a)
+			continue
+		}
+
+		part := &arc{from: a.from, to: a.to, flow: a.flow, capacity: a.flow}
+		a.capacity -= a.flow
+		a.flow = 0
+		a.from.addOutOfTreeNode(a)
+		a.from.growOutOfTree()
+		a.to.growOutOfTree()
+		a.to.addOutOfTreeNode(part)
+		s.splitArcs = append(s.splitArcs, splitArc{a, part})
+	}
+
+	return nil
+}
+
+// RunIncrements traces what raising the capacity of one arc buys. Starting
+// from the solved graph, it increases the capacity of the arc (arc[0], arc[1])
+// by each of 'increments' in turn and solves again, warm started from the
+// previous flows; see SetInitialFlow. A line "<step> <capacity> <max flow>"
+// is written to 'w' for the solved graph, step 0, and after each increment,
+// so the output can be plotted directly. Increments must not be negative.
+// With parallel arcs the first in the order of Flows is increased. The
+// Session is left with the last graph and its solution. It returns
+// ErrNoSolution if the Session has not processed any data.
+func (s *Session) RunIncrements(arc [2]uint, increments []int, w io.Writer) error {
+	if !s.solved {
+		return ErrNoSolution
+	}
+	if s.hasLowerBounds() {
+		return errors.New("capacity increments can't be used with mandatory arcs")
+	}
+	for _, v := range increments {
+		if v < 0 {
+			return fmt.Errorf("negative capacity increment %d", v)
+		}
+	}
+
+	first := s.firstArc(arc[0], arc[1])
+	if first == nil {
+		return fmt.Errorf("arc (%d, %d) is not in the graph", arc[0], arc[1])
+	}
+	arcs := make([]A, s.numArcs)
+	var target int
+	for i, a := range s.loadOrder() {
+		arcs[i] = A{From: a.from.number, To: a.to.number, Capacity: a.capacity}
+		if a == first {
+			target = i
+		}
+	}
+
+	nodes := []N{{s.source, "s"}, {s.sink, "t"}}
+	inputNodes := s.inputNodes
+	if _, err := fmt.Fprintf(w, "0 %d %d\n", arcs[target].Capacity, s.maxFlow); err != nil {
+		return err
+	}
+	for step, inc := range increments {
+		for i, a := range s.loadOrder() {
+			arcs[i].Flow = a.flow
+		}
+		arcs[target].Capacity += inc
+		if err := s.loadNA(s.numNodes, uint(len(arcs)), nodes, arcs); err != nil {
+			return err
+		}
+		s.inputNodes = inputNodes
+		if err := s.SetInitialFlow(arcs); err != nil {
+			return err
+		}
+		if err := s.solve(context.Background()); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%d %d %d\n", step+1, arcs[target].Capacity, s.maxFlow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstArc returns the first arc of arcList from 'from' to 'to', or nil.
+func (s *Session) firstArc(from, to uint) *arc {
+	for _, a := range s.arcList[:s.numArcs] {
+		if a.from.number == from && a.to.number == to {
+			return a
+		}
+	}
+	return nil
+}
+
+// mergeSplitArcs restores the arcs split by applyInitialFlow, adding the
+// flow of each part to its original arc.
+func (s *Session) mergeSplitArcs() {
+	for _, v := range s.splitArcs {
+		v.orig.capacity += v.part.capacity
+		v.orig.flow += v.part.flow
+	}
+	s.splitArcs = nil
+}
+
+// (*node) removeOutOfTreeNode removes 'out' from the out-of-tree arcs.
+func (n *node) removeOutOfTreeNode(out *arc) {
+	for i := uint(0); i < n.numberOutOfTree; i++ {
+		if n.outOfTree[i] == out {
+			n.numberOutOfTree--
+			n.outOfTree[i] = n.outOfTree[n.numberOutOfTree]
+			return
+		}
+	}
+}
+
+// (*node) growOutOfTree makes room for one more adjacent arc.
+func (n *node) growOutOfTree() {
+	n.numAdjacent++
+	n.outOfTree = append(n.outOfTree, nil)
+}
+
+// UpdateArcCapacity sets the capacity of the arc (from, to) to 'capacity'
+// for sensitivity analysis; call Resolve to solve the changed graph without
+// reading the input again. With parallel arcs the first in the order of
+// Flows is changed. If the graph was solved, the solution is dropped until
+// Resolve. It returns ErrNoGraph if the Session has no graph.
+func (s *Session) UpdateArcCapacity(from, to uint, capacity int) error {
+	if !s.solved && !s.loaded && !s.updated {
+		return ErrNoGraph
+	}
+	if s.hasLowerBounds() {
+		return errors.New("capacities can't be updated with mandatory arcs")
+	}
+	if s.interrupted {
+		return errors.New("capacities can't be updated while a solve is interrupted")
+	}
+	if capacity < 0 {
+		return fmt.Errorf("negative capacity %d on arc (%d, %d)", capacity, from, to)
+	}
+	a := s.firstArc(from, to)
+	if a == nil {
+		return fmt.Errorf("arc (%d, %d) is not in the graph", from, to)
+	}
+	a.capacity = capacity
+	if s.loaded && from == s.source && to == s.sink {
+		a.flow = capacity // loaded source-sink arcs are saturated
+	}
+	if s.solved {
+		s.solved, s.updated = false, true
+	}
+	return nil
+}
+
+// Resolve solves the graph after UpdateArcCapacity. The graph is reloaded
+// from the Session's arcs rather than the input and, if the previous flows
+// are within the new capacities, the solve is warm started from them; see
+// SetInitialFlow. A loaded graph that hasn't been solved is just solved. It
+// returns ErrNoGraph if there is no graph to solve.
+func (s *Session) Resolve() error {
+	if !s.updated {
+		if !s.loaded {
+			return ErrNoGraph
+		}
+		return s.solve(context.Background())
+	}
+
+	warm := true
+	arcs := make([]A, s.numArcs)
+	for i, a := range s.loadOrder() {
+		arcs[i] = A{From: a.from.number, To: a.to.number, Capacity: a.capacity, Flow: a.flow}
+		if a.flow > a.capacity {
+			warm = false
+		}
+	}
+	nodes := []N{{s.source, "s"}, {s.sink, "t"}}
+	inputNodes := s.inputNodes
+	if err := s.loadNA(s.numNodes, uint(len(arcs)), nodes, arcs); err != nil {
+		return err
+	}
+	s.inputNodes = inputNodes
+	if warm {
+		if err := s.SetInitialFlow(arcs); err != nil {
+			return err
+		}
+	}
+	return s.solve(context.Background())
+}
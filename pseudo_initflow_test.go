@@ -0,0 +1,184 @@
+// pseudo_initflow_test.go - warm start tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// Seed the sample graph with a flow of 10 and confirm the solve still
+// finds the max flow of 15.
+func TestSetInitialFlow(t *testing.T) {
+	seed := []A{
+		{From: 1, To: 2, Flow: 5},
+		{From: 2, To: 4, Flow: 5},
+		{From: 4, To: 6, Flow: 5}, // split: capacity 15
+		{From: 1, To: 3, Flow: 5}, // source arc
+		{From: 3, To: 5, Flow: 5}, // saturated
+		{From: 5, To: 6, Flow: 5}, // sink arc
+	}
+
+	for _, c := range []Context{{}, {LowestLabel: true}, {FifoBuckets: true}, {LowestLabel: true, FifoBuckets: true}} {
+		s := NewSession(c)
+		if err := s.SetInitialFlow(seed); err != nil {
+			t.Fatal(err)
+		}
+		results, err := s.Run("_data/dimacsMaxf.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := strings.Join(results, "\n")
+		if !strings.Contains(out, "c Solution checks as feasible") ||
+			!strings.Contains(out, "c Solution checks as optimal") ||
+			!strings.Contains(out, "\ns 15\n") {
+			fmt.Println(c, "got:\n", out)
+			t.Fatal()
+		}
+		// the arcs split by the warm start are restored
+		for _, a := range s.arcList {
+			if a.from.number == 4 && a.to.number == 6 && a.capacity != 15 {
+				fmt.Println("arc (4, 6) capacity - want: 15 got:", a.capacity)
+				t.Fatal()
+			}
+		}
+		if len(s.splitArcs) != 0 || s.initialFlow != nil {
+			fmt.Println("warm start state not cleared")
+			t.Fatal()
+		}
+	}
+}
+
+func TestSetInitialFlowErrors(t *testing.T) {
+	s := NewSession(Context{})
+	if err := s.SetInitialFlow([]A{{From: 1, To: 2, Flow: -1}}); err == nil {
+		fmt.Println("want: negative flow error")
+		t.Fatal()
+	}
+
+	bad := map[string][]A{
+		"exceeds capacity": {{From: 1, To: 2, Flow: 6}, {From: 2, To: 4, Flow: 6}, {From: 4, To: 6, Flow: 6}},
+		"not balanced":     {{From: 1, To: 2, Flow: 5}},
+		"not in the graph": {{From: 2, To: 3, Flow: 1}},
+	}
+	for msg, seed := range bad {
+		if err := s.SetInitialFlow(seed); err != nil {
+			t.Fatal(err)
+		}
+		_, err := s.Run("_data/dimacsMaxf.txt")
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			fmt.Println("want:", msg, "got:", err)
+			t.Fatal()
+		}
+	}
+}
+
+func TestRunIncrements(t *testing.T) {
+	s := NewSession(Context{})
+	var buf bytes.Buffer
+	if err := s.RunIncrements([2]uint{3, 4}, []int{5}, &buf); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RunIncrements([2]uint{3, 7}, []int{5}, &buf); err == nil {
+		fmt.Println("want: error for an arc not in the graph")
+		t.Fatal()
+	}
+
+	// 3->4 is in the min cut; at 10 the cut moves to 1->2, 1->3
+	if err := s.RunIncrements([2]uint{3, 4}, []int{5, 5, 0}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "0 5 15\n1 10 20\n2 15 20\n3 15 20\n"; buf.String() != want {
+		fmt.Println("want:\n", want, "got:\n", buf.String())
+		t.Fatal()
+	}
+	if err := s.AssertOptimal(); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range s.Flows() {
+		if a.From == 3 && a.To == 4 && a.Capacity != 15 {
+			fmt.Println("want: capacity 15 got:", a)
+			t.Fatal()
+		}
+	}
+}
+
+func TestRunIncrementsMultiTerminal(t *testing.T) {
+	s := NewSession(Context{MultiTerminal: true})
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(multiTerminalData)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	// (4, 6) is in the min cut; 2->4 limits it to 2
+	var buf bytes.Buffer
+	if err := s.RunIncrements([2]uint{4, 6}, []int{1, 1}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "0 1 8\n1 2 9\n2 3 9\n"; buf.String() != want {
+		fmt.Println("want:\n", want, "got:\n", buf.String())
+		t.Fatal()
+	}
+	if !inputFlows(s) {
+		fmt.Println("got:", s.Flows())
+		t.Fatal()
+	}
+}
+
+func TestResolve(t *testing.T) {
+	s := NewSession(Context{})
+	if err := s.UpdateArcCapacity(3, 4, 10); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+	if err := s.Resolve(); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateArcCapacity(3, 7, 10); err == nil {
+		fmt.Println("want: error for an arc not in the graph")
+		t.Fatal()
+	}
+
+	// 3->4 is the bottleneck; at 10 the cut moves to 1->2, 1->3
+	if err := s.UpdateArcCapacity(3, 4, 10); err != nil {
+		t.Fatal(err)
+	}
+	if s.Flows() != nil {
+		fmt.Println("want: no flows until Resolve")
+		t.Fatal()
+	}
+	if err := s.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 20 {
+		fmt.Println("want: 20 got:", s.maxFlow)
+		t.Fatal()
+	}
+	if err := s.AssertOptimal(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the flow on 3->4 no longer fits, so the re-solve is cold
+	if err := s.UpdateArcCapacity(3, 4, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Resolve(); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 10 {
+		fmt.Println("want: 10 got:", s.maxFlow)
+		t.Fatal()
+	}
+	if err := s.AssertOptimal(); err != nil {
+		t.Fatal(err)
+	}
+}
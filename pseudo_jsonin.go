@@ -0,0 +1,65 @@
+// pseudo_jsonin.go - a JSON alternative to DIMACS input.
+
+package pseudo
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// jsonGraph is the input of RunJSONInput.
+type jsonGraph struct {
+	Nodes  uint        `json:"nodes"`
+	Arcs   *uint       `json:"arcs"`
+	Source *uint       `json:"source"`
+	Sink   *uint       `json:"sink"`
+	Edges  []*jsonEdge `json:"edges"`
+}
+
+type jsonEdge struct {
+	From     *uint `json:"from"`
+	To       *uint `json:"to"`
+	Capacity *int  `json:"capacity"`
+}
+
+// RunJSONInput is RunReader for a graph given as a JSON object rather than
+// DIMACS text:
+//
+//	{"nodes": 6, "arcs": 8, "source": 1, "sink": 6,
+//	  "edges": [{"from": 1, "to": 2, "capacity": 5}, ...]}
+//
+// "arcs" is optional; if it is given it must be the number of "edges". The
+// graph is loaded as RunNAWriter loads it, so the N and A values are
+// validated the same way.
+func (s *Session) RunJSONInput(r io.Reader, header ...string) ([]string, error) {
+	var g jsonGraph
+	if err := json.NewDecoder(r).Decode(&g); err != nil {
+		return nil, fmt.Errorf("JSON input: %s", err)
+	}
+	if g.Source == nil {
+		return nil, errors.New("JSON input has no source")
+	}
+	if g.Sink == nil {
+		return nil, errors.New("JSON input has no sink")
+	}
+	if g.Arcs != nil && *g.Arcs != uint(len(g.Edges)) {
+		return nil, inputError(0, ErrArcCount, "declared %d arcs but found %d", *g.Arcs, len(g.Edges))
+	}
+
+	arcs := make([]A, len(g.Edges))
+	for i, e := range g.Edges {
+		if e == nil || e.From == nil || e.To == nil || e.Capacity == nil {
+			return nil, fmt.Errorf("JSON input edge %d doesn't have a from, to and capacity", i+1)
+		}
+		arcs[i] = A{From: *e.From, To: *e.To, Capacity: *e.Capacity}
+	}
+
+	w := new(bytes.Buffer)
+	if err := s.RunNAWriter(g.Nodes, uint(len(arcs)), []N{{*g.Source, "s"}, {*g.Sink, "t"}}, arcs, w, header...); err != nil {
+		return nil, err
+	}
+	return resultLines(w)
+}
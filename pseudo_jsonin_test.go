@@ -0,0 +1,53 @@
+// pseudo_jsonin_test.go - RunJSONInput tests.
+
+package pseudo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunJSONInput(t *testing.T) {
+	s := NewSession(Context{})
+	want, err := s.Run("_data/dimacsMaxf.txt", "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := os.Open("_data/dimacsMaxf.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	got, err := s.RunJSONInput(fh, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+
+	for _, v := range []struct {
+		data, want string
+	}{
+		{`{"nodes": 2, "sink": 2, "edges": [{"from": 1, "to": 2, "capacity": 5}]}`, "JSON input has no source"},
+		{`{"nodes": 2, "source": 1, "edges": [{"from": 1, "to": 2, "capacity": 5}]}`, "JSON input has no sink"},
+		{`{"nodes": 2, "source": 1, "sink": 2, "edges": [{"from": 1, "capacity": 5}]}`, "JSON input edge 1 doesn't have a from, to and capacity"},
+		{`{"nodes": 2, "arcs": 2, "source": 1, "sink": 2, "edges": [{"from": 1, "to": 2, "capacity": 5}]}`, "declared 2 arcs but found 1"},
+		{`{"nodes": 2, "source": 1, "sink": 2, "edges": [{"from": 1, "to": 3, "capacity": 5}]}`, "arc (1, 3) has a node out of range 1-2"},
+		{`{"nodes": 2, "source": 1, "sink": 2, "edges": [{"from": 1, "to": 2, "capacity": "5"}]}`, "JSON input: "},
+	} {
+		if _, err = s.RunJSONInput(strings.NewReader(v.data)); err == nil || !strings.HasPrefix(err.Error(), v.want) {
+			fmt.Println("want:", v.want, "got:", err)
+			t.Fatal()
+		}
+	}
+	if _, err = s.RunJSONInput(strings.NewReader(`{"nodes": 2, "arcs": 2, "source": 1, "sink": 2, "edges": []}`)); !errors.Is(err, ErrArcCount) {
+		fmt.Println("want:", ErrArcCount, "got:", err)
+		t.Fatal()
+	}
+}
@@ -0,0 +1,163 @@
+// pseudo_mandatory.go - arcs that must carry a minimum flow.
+
+package pseudo
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// AddMustUseArc adds an arc that must carry at least 'minFlow', or 1 if
+// 'minFlow' is less than 1, in the solution. The max flow is then the
+// largest flow that satisfies all such minimums; if there is none, the
+// solve returns ErrNoFeasibleFlow.
+func (si *SessionInitializer) AddMustUseArc(from, to uint, capacity, minFlow int) {
+	if minFlow < 1 {
+		minFlow = 1
+	}
+	if !si.checkArc(from, to, capacity) {
+		return
+	}
+	if si.deferred {
+		si.pending = append(si.pending, pendingArc{A{From: from, To: to, Capacity: capacity}, minFlow})
+		return
+	}
+	si.addArc(from, to, capacity, minFlow)
+}
+
+// hasLowerBounds reports whether any arc is mandatory.
+func (s *Session) hasLowerBounds() bool {
+	for i := uint(0); i < s.numArcs; i++ {
+		if s.arcList[i].lower > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// loadSlots returns the arcList index that loadNA, or addArc, stores each
+// of 'arcs' at.
+func loadSlots(arcs []A) []uint {
+	slots := make([]uint, len(arcs))
+	if len(arcs) == 0 {
+		return slots
+	}
+	first, last := uint(0), uint(len(arcs))-1
+	for i, v := range arcs {
+		if (v.From+v.To)%2 != 0 {
+			slots[i] = first
+			first++
+		} else {
+			slots[i] = last
+			last--
+		}
+	}
+	return slots
+}
+
+// solveAux solves the graph 'arcs' of 'numNodes' nodes with a new Session
+// and returns the flow on each arc. Its stats are added to those of 's'.
+func (s *Session) solveAux(ctx context.Context, numNodes, source, sink uint, arcs []A) ([]int, error) {
+	aux := NewSession(Context{LowestLabel: s.ctx.LowestLabel, FifoBuckets: s.ctx.FifoBuckets})
+	if err := aux.loadNA(numNodes, uint(len(arcs)), []N{{source, "s"}, {sink, "t"}}, arcs); err != nil {
+		return nil, err
+	}
+	if err := aux.solve(ctx); err != nil {
+		return nil, err
+	}
+
+	s.stats.Pushes += aux.stats.Pushes
+	s.stats.Mergers += aux.stats.Mergers
+	s.stats.Relabels += aux.stats.Relabels
+	s.stats.Gaps += aux.stats.Gaps
+	s.stats.ArcScans += aux.stats.ArcScans
+	if aux.stats.PeakBucketSize > s.stats.PeakBucketSize {
+		s.stats.PeakBucketSize = aux.stats.PeakBucketSize
+	}
+
+	flows := make([]int, len(arcs))
+	for i, slot := range loadSlots(arcs) {
+		flows[i] = aux.arcList[slot].flow
+	}
+	return flows, nil
+}
+
+// solveLowerBounds solves a graph with mandatory arcs by the lower bounds
+// transformation. The first solve finds a feasible flow: each arc carries
+// its minimum plus the flow on an arc of its residual capacity in a graph
+// where a super source supplies, and a super sink absorbs, the imbalance
+// the minimums create and an uncapacitated sink->source arc closes the
+// circulation. The second solve is the max flow in the residual graph of
+// that flow, which is added to it. The cut is then taken from the
+// residual graph; see sourceSet.
+func (s *Session) solveLowerBounds(ctx context.Context) error {
+	if s.initialFlow != nil {
+		s.initialFlow = nil
+		return errors.New("an initial flow can't be used with mandatory arcs")
+	}
+
+	n := s.numNodes
+	superSource, superSink := n+1, n+2
+	balance := make([]int, n)
+	var total int
+	arcs := make([]A, 0, s.numArcs+n+1)
+	for i := uint(0); i < s.numArcs; i++ {
+		a := s.arcList[i]
+		if a.lower > a.capacity {
+			return ErrNoFeasibleFlow
+		}
+		balance[a.to.number-1] += a.lower
+		balance[a.from.number-1] -= a.lower
+		total += a.capacity
+		arcs = append(arcs, A{From: a.from.number, To: a.to.number, Capacity: a.capacity - a.lower})
+	}
+	var demand int
+	for i, b := range balance {
+		if b > 0 {
+			arcs = append(arcs, A{From: superSource, To: uint(i + 1), Capacity: b})
+			demand += b
+		} else if b < 0 {
+			arcs = append(arcs, A{From: uint(i + 1), To: superSink, Capacity: -b})
+		}
+	}
+	arcs = append(arcs, A{From: s.sink, To: s.source, Capacity: total})
+
+	flows, err := s.solveAux(ctx, n+2, superSource, superSink, arcs)
+	if err != nil {
+		return err
+	}
+	var supplied int
+	for i := s.numArcs; i < uint(len(arcs)); i++ {
+		if arcs[i].From == superSource {
+			supplied += flows[i]
+		}
+	}
+	if supplied != demand {
+		return ErrNoFeasibleFlow
+	}
+	for i := uint(0); i < s.numArcs; i++ {
+		s.arcList[i].flow = s.arcList[i].lower + flows[i]
+	}
+	s.times.initialize = time.Now()
+
+	// augment in the residual graph: arc 2i is the spare capacity
+	// of arc i and arc 2i+1 the flow above its minimum
+	arcs = arcs[:0]
+	for i := uint(0); i < s.numArcs; i++ {
+		a := s.arcList[i]
+		arcs = append(arcs,
+			A{From: a.from.number, To: a.to.number, Capacity: a.capacity - a.flow},
+			A{From: a.to.number, To: a.from.number, Capacity: a.flow - a.lower})
+	}
+	if flows, err = s.solveAux(ctx, n, s.source, s.sink, arcs); err != nil {
+		return err
+	}
+	s.times.flow = time.Now()
+	for i := uint(0); i < s.numArcs; i++ {
+		s.arcList[i].flow += flows[2*i] - flows[2*i+1]
+	}
+	s.times.recflow = time.Now()
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// pseudo_mandatory_test.go - mandatory arc tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// mustUseGraph loads the graph
+//
+//	1 -> 2 (10)  2 -> 4 (10)
+//	1 -> 3 (10)  3 -> 4 (10)
+//	2 -> 3 (5), mandatory with a minimum flow of 'min' if 'min' > 0
+//
+// with source 1 and sink 4.
+func mustUseGraph(s *Session, min int) {
+	si := NewSessionInitializer(s)
+	si.Init(4, 5)
+	si.SetSource(1)
+	si.SetSink(4)
+	si.AddArc(1, 2, 10)
+	si.AddArc(1, 3, 10)
+	si.AddArc(2, 4, 10)
+	si.AddArc(3, 4, 10)
+	if min > 0 {
+		si.AddMustUseArc(2, 3, 5, min)
+	} else {
+		si.AddArc(2, 3, 5)
+	}
+	si.Complete()
+}
+
+func TestMustUseArc(t *testing.T) {
+	s := NewSession(Context{})
+	mustUseGraph(s, 0)
+	var buf bytes.Buffer
+	if err := s.RunWriter(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if s.minCut() != 20 {
+		fmt.Println("want: 20 got:", s.minCut())
+		t.Fatal()
+	}
+
+	// 2->3 must carry all 5 of its capacity; that flow can only
+	// leave 3 on 3->4, leaving 5 of capacity there for 1->3
+	s = NewSession(Context{})
+	mustUseGraph(s, 5)
+	buf.Reset()
+	if err := s.RunWriter(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("c Solution checks as optimal\nc \nc Solution\ns 15\n")) {
+		fmt.Println("want: s 15 got:\n", buf.String())
+		t.Fatal()
+	}
+	want := map[[2]uint]int{{1, 2}: 10, {1, 3}: 5, {2, 4}: 5, {3, 4}: 10, {2, 3}: 5}
+	for _, a := range s.arcList {
+		if a.flow != want[[2]uint{a.from.number, a.to.number}] {
+			fmt.Println("arc", a.from.number, a.to.number, "want:", want[[2]uint{a.from.number, a.to.number}], "got:", a.flow)
+			t.Fatal()
+		}
+	}
+	if fmt.Sprint(s.Cut()) != "[1 3]" {
+		fmt.Println("want: [1 3] got:", s.Cut())
+		t.Fatal()
+	}
+}
+
+func TestMustUseArcInfeasible(t *testing.T) {
+	// flow into 3 can't reach the sink
+	s := NewSession(Context{})
+	si := NewSessionInitializer(s)
+	si.Init(4, 3)
+	si.SetSource(1)
+	si.SetSink(4)
+	si.AddArc(1, 2, 10)
+	si.AddArc(2, 4, 10)
+	si.AddMustUseArc(2, 3, 5, 0)
+	si.Complete()
+
+	var buf bytes.Buffer
+	if err := s.RunWriter(&buf); err != ErrNoFeasibleFlow {
+		fmt.Println("want:", ErrNoFeasibleFlow, "got:", err)
+		t.Fatal()
+	}
+	if s.FlowBalance() != nil {
+		fmt.Println("infeasible solve reported a result")
+		t.Fatal()
+	}
+}
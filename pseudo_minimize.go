@@ -0,0 +1,99 @@
+// pseudo_minimize.go - reducing a failing graph to a small reproducer.
+
+package pseudo
+
+import "errors"
+
+// GraphSource is a graph in the form RunNAWriter takes it.
+type GraphSource struct {
+	NumNodes uint
+	Nodes    []N // the source and sink
+	Arcs     []A
+}
+
+// MinimizeFailure reduces the loaded graph to a small graph on which 'check'
+// still fails, to make a reproducer for a bug report. 'check' is supplied
+// by the user: it is passed a new Session, with the Session's Context and a
+// candidate graph loaded but not solved, and returns true if the failure
+// still occurs - e.g., it calls RunWriter and compares the max flow with a
+// known value. A panic in 'check' counts as a failure.
+//
+// Arcs are removed by delta debugging, so the result fails but removing any
+// one of its arcs does not. Nodes that are left without arcs are then
+// dropped and the rest renumbered, if the failure still occurs. Mandatory
+// arcs are treated as ordinary arcs. It returns ErrNoGraph if no graph has
+// been loaded and an error if 'check' does not fail on the loaded graph.
+func (s *Session) MinimizeFailure(check func(*Session) bool) (GraphSource, error) {
+	if s.numNodes == 0 {
+		return GraphSource{}, ErrNoGraph
+	}
+
+	nodes := []N{{s.source, "s"}, {s.sink, "t"}}
+	arcs := make([]A, 0, s.numArcs)
+	for _, a := range s.loadOrder() {
+		arcs = append(arcs, A{From: a.from.number, To: a.to.number, Capacity: a.capacity})
+	}
+	g := GraphSource{NumNodes: s.numNodes, Nodes: nodes, Arcs: arcs}
+	if !s.fails(check, g) {
+		return GraphSource{}, errors.New("check does not fail on the loaded graph")
+	}
+
+	// ddmin: remove ever smaller chunks of arcs while the failure occurs
+	for n := 2; len(g.Arcs) > 0; {
+		if n > len(g.Arcs) {
+			n = len(g.Arcs)
+		}
+		reduced := false
+		size := (len(g.Arcs) + n - 1) / n
+		for start := 0; start < len(g.Arcs); start += size {
+			end := start + size
+			if end > len(g.Arcs) {
+				end = len(g.Arcs)
+			}
+			rest := append(append([]A{}, g.Arcs[:start]...), g.Arcs[end:]...)
+			if s.fails(check, GraphSource{g.NumNodes, g.Nodes, rest}) {
+				g.Arcs = rest
+				reduced = true
+				break
+			}
+		}
+		if reduced {
+			if n > 2 {
+				n--
+			}
+			continue
+		}
+		if n == len(g.Arcs) {
+			break // no single arc can be removed
+		}
+		n *= 2
+	}
+
+	// drop unused nodes
+	compacted, mapping, numNodes := CompactNodeNumbers(g.Arcs, g.Nodes...)
+	c := GraphSource{
+		NumNodes: numNodes,
+		Nodes:    []N{{mapping[s.source], "s"}, {mapping[s.sink], "t"}},
+		Arcs:     compacted,
+	}
+	if numNodes < g.NumNodes && s.fails(check, c) {
+		g = c
+	}
+
+	return g, nil
+}
+
+// fails loads 'g' into a new Session and reports whether 'check' fails on
+// it or panics.
+func (s *Session) fails(check func(*Session) bool, g GraphSource) (failed bool) {
+	sub := NewSession(s.ctx)
+	if err := sub.loadNA(g.NumNodes, uint(len(g.Arcs)), g.Nodes, g.Arcs); err != nil {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			failed = true
+		}
+	}()
+	return check(sub)
+}
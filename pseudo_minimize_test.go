@@ -0,0 +1,73 @@
+// pseudo_minimize_test.go - MinimizeFailure tests.
+
+package pseudo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestMinimizeFailure(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.MinimizeFailure(nil); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// "fails" while the max flow is at least 10
+	check := func(sub *Session) bool {
+		if err := sub.RunWriter(ioutil.Discard); err != nil {
+			return false
+		}
+		mf, _ := sub.MaxFlow()
+		return mf >= 10
+	}
+	if _, err := s.MinimizeFailure(func(*Session) bool { return false }); err == nil {
+		fmt.Println("want: error for a check that doesn't fail")
+		t.Fatal()
+	}
+	g, err := s.MinimizeFailure(check)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two paths of capacity 5 sharing an arc
+	if g.NumNodes != 5 || len(g.Arcs) != 5 {
+		fmt.Println("want: 5 nodes and 5 arcs got:", g)
+		t.Fatal()
+	}
+	fails := func(arcs []A) bool {
+		return s.fails(check, GraphSource{g.NumNodes, g.Nodes, arcs})
+	}
+	if !fails(g.Arcs) {
+		fmt.Println("minimized graph doesn't fail:", g)
+		t.Fatal()
+	}
+	for i := range g.Arcs {
+		if fails(append(append([]A{}, g.Arcs[:i]...), g.Arcs[i+1:]...)) {
+			fmt.Println("arc", g.Arcs[i], "can be removed from:", g)
+			t.Fatal()
+		}
+	}
+
+	// a panic on the arc 4->6; renumbering the nodes hides it, so they are kept
+	g, err = s.MinimizeFailure(func(sub *Session) bool {
+		for _, a := range sub.arcList {
+			if a.from.number == 4 && a.to.number == 6 {
+				panic("arc 4->6")
+			}
+		}
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := GraphSource{NumNodes: 6, Nodes: []N{{1, "s"}, {6, "t"}}, Arcs: []A{{From: 4, To: 6, Capacity: 15}}}
+	if fmt.Sprint(g) != fmt.Sprint(want) {
+		fmt.Println("want:", want, "got:", g)
+		t.Fatal()
+	}
+}
@@ -0,0 +1,128 @@
+// pseudo_multiterminal.go - graphs with several sources and sinks.
+
+package pseudo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// terminal is a source or sink of a multi-terminal graph. A capacity less
+// than 0 means the arc from the super-source, or to the super-sink, is
+// unbounded.
+type terminal struct {
+	node     uint
+	capacity int
+}
+
+// parseTerminalLine parses an "n <node> s|t [capacity]" line of a
+// Context.MultiTerminal graph. With no capacity it is returned as -1.
+func parseTerminalLine(line []byte) (node uint, which string, capacity int, err error) {
+	vals := strings.Fields(string(line))
+	if len(vals) != 3 && len(vals) != 4 {
+		return 0, "", 0, fmt.Errorf("n entry doesn't have 2 or 3 values, has: %d", len(vals)-1)
+	}
+	n, err := strconv.ParseUint(vals[1], 10, 64)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	capacity = -1
+	if len(vals) == 4 {
+		if capacity, err = strconv.Atoi(vals[3]); err != nil {
+			return 0, "", 0, err
+		}
+		if capacity < 0 {
+			return 0, "", 0, fmt.Errorf("negative capacity %d", capacity)
+		}
+	}
+	return uint(n), vals[2], capacity, nil
+}
+
+// loadMultiTerminal loads a graph of s.numNodes nodes with 'arcs'. If there
+// is more than one source a super-source, node numNodes+1, is appended with
+// an arc to each source; likewise a super-sink with an arc from each sink.
+// An unbounded arc gets the total capacity of the arcs out of its source,
+// or into its sink, which is all the flow it can carry. Repeated terminals
+// are merged, keeping the last capacity.
+func (s *Session) loadMultiTerminal(sources, sinks []terminal, arcs []A) error {
+	numNodes := s.numNodes
+	sources, sinks = uniqueTerminals(sources), uniqueTerminals(sinks)
+	isSource := make(map[uint]bool, len(sources))
+	for _, v := range sources {
+		isSource[v.node] = true
+	}
+	for _, v := range sinks {
+		if isSource[v.node] {
+			return inputError(0, ErrTerminals, "node %d is both a source and a sink", v.node)
+		}
+	}
+
+	out := make(map[uint]int)
+	in := make(map[uint]int)
+	for _, a := range arcs {
+		out[a.From] += a.Capacity
+		in[a.To] += a.Capacity
+	}
+
+	nn := numNodes
+	source, sink := sources[0].node, sinks[0].node
+	if len(sources) > 1 {
+		nn++
+		source = nn
+		for _, v := range sources {
+			if v.capacity < 0 {
+				v.capacity = out[v.node]
+			}
+			arcs = append(arcs, A{From: source, To: v.node, Capacity: v.capacity})
+		}
+	}
+	if len(sinks) > 1 {
+		nn++
+		sink = nn
+		for _, v := range sinks {
+			if v.capacity < 0 {
+				v.capacity = in[v.node]
+			}
+			arcs = append(arcs, A{From: v.node, To: sink, Capacity: v.capacity})
+		}
+	}
+
+	// loadNA clears the stats, e.g., SkippedLines, of reading the input
+	nodes := []N{{source, "s"}, {sink, "t"}}
+	stats := s.stats
+	if err := s.loadNA(nn, uint(len(arcs)), nodes, arcs); err != nil {
+		return err
+	}
+	s.stats = stats
+	if nn > numNodes {
+		s.inputNodes = numNodes
+	}
+	return nil
+}
+
+// uniqueTerminals drops repeated terminals; the last capacity is kept.
+func uniqueTerminals(t []terminal) []terminal {
+	index := make(map[uint]int, len(t))
+	result := make([]terminal, 0, len(t))
+	for _, v := range t {
+		if i, ok := index[v.node]; ok {
+			result[i].capacity = v.capacity
+			continue
+		}
+		index[v.node] = len(result)
+		result = append(result, v)
+	}
+	return result
+}
+
+// inputNode reports whether node 'n' is in the input rather than a
+// super-source or super-sink added for a Context.MultiTerminal graph.
+func (s *Session) inputNode(n uint) bool {
+	return s.inputNodes == 0 || n <= s.inputNodes
+}
+
+// inputArc reports whether 'a' is in the input; see inputNode.
+func (s *Session) inputArc(a *arc) bool {
+	return s.inputNode(a.from.number) && s.inputNode(a.to.number)
+}
@@ -0,0 +1,138 @@
+// pseudo_patch.go - editing a loaded graph with a patch.
+
+package pseudo
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ApplyPatch edits the loaded graph with the patch read from 'r'. Each line
+// is an edit: "+ <from> <to> <capacity>" adds an arc, "~ <from> <to>
+// <capacity>" changes the capacity of an arc and "- <from> <to>" removes an
+// arc. For example,
+//
+//	c widen 3->4 and replace 1->2
+//	~ 3 4 10
+//	- 1 2
+//	+ 1 2 7
+//
+// Lines starting with 'c' and empty lines are skipped. With parallel arcs,
+// '~' and '-' apply to the first in the order of Flows; added arcs follow
+// the existing ones. Nodes can't be added, so the nodes of an arc must be in
+// 1 through the number of nodes of the graph. If any line is invalid an
+// error giving its line number is returned and the graph is unchanged.
+// ApplyPatch can't be used with mandatory arcs.
+//
+// The patched graph is loaded but not solved; solve it with RunWriter. The
+// solve starts from scratch. It can be warm started by passing the Flows of
+// the previous solution, taken before ApplyPatch, to SetInitialFlow, but only
+// if they are still a feasible flow: the flow of a removed arc, or over a
+// reduced capacity, can't be seeded, and SetInitialFlow's validation rejects
+// it. Removing arcs that carry flow rules out a warm start.
+func (s *Session) ApplyPatch(r io.Reader) error {
+	if s.numNodes == 0 {
+		return ErrNoGraph
+	}
+	if s.hasLowerBounds() {
+		return errors.New("a patch can't be applied to a graph with mandatory arcs")
+	}
+
+	// arcs are in load order, so that an unpatched arc keeps its place
+	var p patch
+	index := make(map[*arc]int, s.numArcs)
+	for i, a := range s.loadOrder() {
+		p.arcs = append(p.arcs, A{From: a.from.number, To: a.to.number, Capacity: a.capacity})
+		index[a] = i
+	}
+	p.removed = make([]bool, len(p.arcs))
+	for _, a := range s.arcList[:s.numArcs] {
+		p.order = append(p.order, index[a])
+	}
+
+	buf := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		l, err := buf.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if t := bytes.TrimSpace(l); len(t) > 0 && t[0] != 'c' {
+			if err := p.apply(string(t), s.numNodes); err != nil {
+				return fmt.Errorf("patch line %d: %s", line, err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+
+	patched := make([]A, 0, len(p.arcs))
+	for i, a := range p.arcs {
+		if !p.removed[i] {
+			patched = append(patched, a)
+		}
+	}
+	nodes := []N{{s.source, "s"}, {s.sink, "t"}}
+	inputNodes := s.inputNodes
+	if err := s.loadNA(s.numNodes, uint(len(patched)), nodes, patched); err != nil {
+		s.loaded = false
+		return err
+	}
+	s.inputNodes = inputNodes
+	return nil
+}
+
+// patch is the graph being edited by ApplyPatch.
+type patch struct {
+	arcs    []A
+	removed []bool
+	order   []int // indexes of 'arcs' in the order of Flows
+}
+
+// apply applies the edit on line 'l'.
+func (p *patch) apply(l string, numNodes uint) error {
+	vals := strings.Fields(l)
+	want := 4
+	if vals[0] == "-" {
+		want = 3
+	} else if vals[0] != "+" && vals[0] != "~" {
+		return fmt.Errorf("unknown edit: %s", l)
+	}
+	if len(vals) != want {
+		return fmt.Errorf("%s edit doesn't have %d values, has: %d", vals[0], want-1, len(vals)-1)
+	}
+	var v [3]uint64
+	var err error
+	for i, f := range vals[1:] {
+		if v[i], err = strconv.ParseUint(f, 10, 64); err != nil {
+			return err
+		}
+	}
+	from, to, capacity := uint(v[0]), uint(v[1]), int(v[2])
+	if from < 1 || from > numNodes || to < 1 || to > numNodes {
+		return fmt.Errorf("arc (%d, %d) has a node out of range 1-%d", from, to, numNodes)
+	}
+
+	if vals[0] == "+" {
+		p.order = append(p.order, len(p.arcs))
+		p.arcs = append(p.arcs, A{From: from, To: to, Capacity: capacity})
+		p.removed = append(p.removed, false)
+		return nil
+	}
+	for _, i := range p.order {
+		if p.arcs[i].From == from && p.arcs[i].To == to && !p.removed[i] {
+			if vals[0] == "~" {
+				p.arcs[i].Capacity = capacity
+			} else {
+				p.removed[i] = true
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("arc (%d, %d) is not in the graph", from, to)
+}
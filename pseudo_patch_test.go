@@ -0,0 +1,83 @@
+// pseudo_patch_test.go - ApplyPatch tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	s := NewSession(Context{})
+	if err := s.ApplyPatch(strings.NewReader("")); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid patches leave the graph unchanged
+	for _, v := range []struct {
+		patch, want string
+	}{
+		{"~ 1 2 10\n* 1 2\n", "patch line 2: unknown edit: * 1 2"},
+		{"- 1 2 3\n", "patch line 1: - edit doesn't have 2 values, has: 3"},
+		{"+ 1 9 3\n", "patch line 1: arc (1, 9) has a node out of range 1-6"},
+		{"c remove it twice\n- 1 2\n- 1 2\n", "patch line 3: arc (1, 2) is not in the graph"},
+	} {
+		if err := s.ApplyPatch(strings.NewReader(v.patch)); err == nil || err.Error() != v.want {
+			fmt.Println("want:", v.want, "got:", err)
+			t.Fatal()
+		}
+	}
+	if mf, _ := s.MaxFlow(); mf != 15 {
+		fmt.Println("Session changed - max flow want: 15 got:", mf)
+		t.Fatal()
+	}
+
+	// 1->2 removed: 10 through 1->3; 3->4 and 5->6 raised: 15; a new arc 3->6
+	patch := "- 1 2\n~ 3 4 10\n\n~ 5 6 10\n+ 3 6 2\n"
+	if err := s.ApplyPatch(strings.NewReader(patch)); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.RunWriter(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if mf, _ := s.MaxFlow(); mf != 15 || s.AssertOptimal() != nil {
+		fmt.Println("want: 15 got:", mf, s.AssertOptimal())
+		t.Fatal()
+	}
+	want := map[[2]uint]int{
+		{1, 3}: 15, {2, 4}: 5, {2, 5}: 5, {3, 4}: 10, {3, 5}: 5, {4, 6}: 15, {5, 6}: 10, {3, 6}: 2,
+	}
+	flows := s.Flows()
+	if len(flows) != len(want) {
+		fmt.Println("want:", want, "got:", flows)
+		t.Fatal()
+	}
+	for _, a := range flows {
+		if want[[2]uint{a.From, a.To}] != a.Capacity {
+			fmt.Println("want:", want, "got:", flows)
+			t.Fatal()
+		}
+	}
+
+	// the same graph loaded from DIMACS solves the same way
+	var dimacs bytes.Buffer
+	if err := s.WriteDimacs(&dimacs); err != nil {
+		t.Fatal(err)
+	}
+	s2 := NewSession(Context{})
+	if err := s2.RunReadWriter(ioutil.NopCloser(&dimacs), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if mf, _ := s2.MaxFlow(); mf != 15 {
+		fmt.Println("want: 15 got:", mf)
+		t.Fatal()
+	}
+}
@@ -0,0 +1,283 @@
+// pseudo_paths.go - decomposition of a solution into flow paths and cycles.
+
+package pseudo
+
+// FlowPath is a source-sink path, or a cycle, of a flow decomposition and
+// the flow along it. For a cycle the first node is not repeated.
+type FlowPath struct {
+	Nodes []uint
+	Flow  int
+}
+
+// decomposeFlow decomposes the flows of the solution into source-sink paths
+// and cycles. Arcs are followed in arcList order, so the result is the same
+// for the same input and Context; a flow generally has other decompositions.
+func (s *Session) decomposeFlow() (paths, cycles []FlowPath) {
+	rem := make([]int, s.numArcs)
+	out := make([][]uint, s.numNodes)
+	for i := uint(0); i < s.numArcs; i++ {
+		a := s.arcList[i]
+		if a.flow > 0 && a.from != a.to {
+			rem[i] = a.flow
+			out[a.from.number-1] = append(out[a.from.number-1], i)
+		}
+	}
+	next := make([]int, s.numNodes)
+
+	// nextArc returns the next arc out of node 'n' with remaining flow.
+	nextArc := func(n uint) (uint, bool) {
+		o := out[n-1]
+		for ; next[n-1] < len(o); next[n-1]++ {
+			if rem[o[next[n-1]]] > 0 {
+				return o[next[n-1]], true
+			}
+		}
+		return 0, false
+	}
+
+	// take subtracts the bottleneck of 'arcs' and returns it
+	take := func(arcs []uint) int {
+		b := rem[arcs[0]]
+		for _, i := range arcs[1:] {
+			if rem[i] < b {
+				b = rem[i]
+			}
+		}
+		for _, i := range arcs {
+			rem[i] -= b
+		}
+		return b
+	}
+
+	// walk follows arcs with remaining flow from 'start', recording cycles as
+	// they close, until it reaches 'stop' or a node without remaining flow out.
+	// It returns the path walked and whether 'stop' was reached.
+	walk := func(start, stop uint) ([]uint, []uint, bool) {
+		nodes := []uint{start}
+		var arcs []uint
+		pos := map[uint]int{start: 0}
+		for {
+			u := nodes[len(nodes)-1]
+			if u == stop {
+				return nodes, arcs, true
+			}
+			i, ok := nextArc(u)
+			if !ok {
+				return nodes, arcs, false
+			}
+			v := s.arcList[i].to.number
+			if p, ok := pos[v]; ok {
+				// close the cycle v ... u -> v
+				cyc := append(append([]uint{}, arcs[p:]...), i)
+				cnodes := append([]uint{}, nodes[p:]...)
+				cycles = append(cycles, FlowPath{cnodes, take(cyc)})
+				for _, n := range nodes[p+1:] {
+					delete(pos, n)
+				}
+				nodes, arcs = nodes[:p+1], arcs[:p]
+				continue
+			}
+			pos[v] = len(nodes)
+			nodes = append(nodes, v)
+			arcs = append(arcs, i)
+		}
+	}
+
+	for {
+		nodes, arcs, ok := walk(s.source, s.sink)
+		if !ok || len(arcs) == 0 {
+			break
+		}
+		paths = append(paths, FlowPath{nodes, take(arcs)})
+	}
+
+	// what is left is circulation
+	for n := uint(1); n <= s.numNodes; n++ {
+		for {
+			if _, ok := nextArc(n); !ok {
+				break
+			}
+			walk(n, 0)
+		}
+	}
+
+	return paths, cycles
+}
+
+// Decompose returns a decomposition of the flows of the solution into
+// source-sink paths and cycles: the flow on each arc is the sum of the
+// flows of the paths and cycles that use it, and the path flows sum to the
+// max flow. Arcs are followed in the order of the "f" lines of Run, so the
+// decomposition is the same for the same input and Context, though a flow
+// generally has others. Cycles are found while walking the paths. It
+// returns ErrNoSolution if the Session has not processed any data.
+func (s *Session) Decompose() (paths, cycles []FlowPath, err error) {
+	if !s.solved {
+		return nil, nil, ErrNoSolution
+	}
+	paths, cycles = s.decomposeFlow()
+	return paths, cycles, nil
+}
+
+// PathStats summarizes the lengths, in arcs, of the source-sink paths in a
+// decomposition of the solution: the number of paths and their average and
+// maximum length. A flow can be decomposed in more than one way; the
+// decomposition used follows arcs in input order, so the stats are
+// reproducible for the same input and Context but may differ between Contexts.
+// All values are 0 if the Session has not processed any data.
+func (s *Session) PathStats() (count int, avgLen, maxLen float64) {
+	if !s.solved {
+		return 0, 0, 0
+	}
+
+	paths, _ := s.decomposeFlow()
+	var total int
+	for _, p := range paths {
+		l := len(p.Nodes) - 1
+		total += l
+		if float64(l) > maxLen {
+			maxLen = float64(l)
+		}
+	}
+	if len(paths) > 0 {
+		avgLen = float64(total) / float64(len(paths))
+	}
+	return len(paths), avgLen, maxLen
+}
+
+// STBridges returns the arcs that every source-sink path of the flow passes
+// through. Precisely, an arc is returned if it carries flow and removing it
+// disconnects the sink from the source in the subgraph of arcs that carry
+// flow; all of the solution's flow then depends on that single arc. These
+// are not the min cut arcs: a bridge needn't be saturated, and a cut arc
+// isn't a bridge if flow also crosses the cut elsewhere. The arcs are in
+// path order from the source and have Flow set. It returns nil if there
+// is no flow or the Session has not processed any data.
+func (s *Session) STBridges() []A {
+	if !s.solved {
+		return nil
+	}
+
+	out := make([][]*arc, s.numNodes)
+	for i := uint(0); i < s.numArcs; i++ {
+		a := s.arcList[i]
+		if a.flow > 0 && a.from != a.to {
+			out[a.from.number-1] = append(out[a.from.number-1], a)
+		}
+	}
+
+	// a source-sink path; only its arcs can be bridges
+	via := make([]*arc, s.numNodes)
+	seen := make([]bool, s.numNodes)
+	seen[s.source-1] = true
+	queue := []uint{s.source}
+	for len(queue) > 0 && !seen[s.sink-1] {
+		n := queue[0]
+		queue = queue[1:]
+		for _, a := range out[n-1] {
+			if !seen[a.to.number-1] {
+				seen[a.to.number-1] = true
+				via[a.to.number-1] = a
+				queue = append(queue, a.to.number)
+			}
+		}
+	}
+	if !seen[s.sink-1] {
+		return nil
+	}
+	var path []*arc
+	for n := s.sink; n != s.source; n = via[n-1].from.number {
+		path = append([]*arc{via[n-1]}, path...)
+	}
+	onPath := make(map[uint]int, len(path)+1) // node number -> path index
+	onPath[s.source] = 0
+	for i, a := range path {
+		onPath[a.to.number] = i + 1
+	}
+
+	// Going along the path, 'reach' is the furthest path node reachable
+	// from the nodes so far without the path's own arcs. Path arc i is
+	// a bridge if nothing before it reaches beyond its tail.
+	var bridges []A
+	visited := make([]bool, s.numNodes)
+	var reach int
+	for i, p := range path {
+		visited[p.from.number-1] = true
+		stack := []uint{p.from.number}
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, a := range out[n-1] {
+				if onPathArc(a, path, onPath) {
+					continue
+				}
+				if j, ok := onPath[a.to.number]; ok {
+					if j > reach {
+						reach = j
+					}
+					continue
+				}
+				if !visited[a.to.number-1] {
+					visited[a.to.number-1] = true
+					stack = append(stack, a.to.number)
+				}
+			}
+		}
+		if reach <= i {
+			bridges = append(bridges, A{From: p.from.number, To: p.to.number, Capacity: p.capacity, Flow: p.flow})
+		}
+	}
+	return bridges
+}
+
+// onPathArc reports whether 'a' is one of the arcs of 'path'.
+func onPathArc(a *arc, path []*arc, onPath map[uint]int) bool {
+	i, ok := onPath[a.from.number]
+	return ok && i < len(path) && path[i] == a
+}
+
+// FlowsByLayer returns the arcs that carry flow, as Flows does, grouped by
+// the layer of their from node: its distance, in arcs, from the source in
+// the graph of the arcs that carry flow. Arcs out of the source are in layer
+// 0, arcs out of the nodes they reach first in layer 1, and so on. An arc
+// from a node that the source can't reach along arcs with flow, e.g., on a
+// circulation, is not included. Within a layer arcs are in the order of
+// Flows. It returns nil if the Session has not processed any data.
+func (s *Session) FlowsByLayer() map[uint][]A {
+	if !s.solved {
+		return nil
+	}
+
+	out := make([][]*arc, s.numNodes)
+	for _, a := range s.arcList[:s.numArcs] {
+		if a.flow > 0 {
+			out[a.from.number-1] = append(out[a.from.number-1], a)
+		}
+	}
+
+	// BFS from the source
+	layer := make([]int, s.numNodes)
+	for i := range layer {
+		layer[i] = -1
+	}
+	layer[s.source-1] = 0
+	queue := []uint{s.source}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, a := range out[n-1] {
+			if to := a.to.number; layer[to-1] < 0 {
+				layer[to-1] = layer[n-1] + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	layers := make(map[uint][]A)
+	for _, a := range s.arcList[:s.numArcs] {
+		if l := layer[a.from.number-1]; a.flow > 0 && l >= 0 {
+			layers[uint(l)] = append(layers[uint(l)], A{From: a.from.number, To: a.to.number, Capacity: a.capacity, Flow: a.flow})
+		}
+	}
+	return layers
+}
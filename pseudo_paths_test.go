@@ -0,0 +1,173 @@
+// pseudo_paths_test.go - flow decomposition tests.
+
+package pseudo
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPathStats(t *testing.T) {
+	s := NewSession(Context{})
+	if count, _, _ := s.PathStats(); count != 0 {
+		fmt.Println("want: 0 paths before Run")
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// paths: 1-2-4-6 (5), 1-3-4-6 (5), 1-3-5-6 (5)
+	count, avgLen, maxLen := s.PathStats()
+	if count != 3 || avgLen != 3 || maxLen != 3 {
+		fmt.Println("want: 3 3 3 got:", count, avgLen, maxLen)
+		t.Fatal()
+	}
+
+	var total int
+	paths, cycles := s.decomposeFlow()
+	for _, p := range paths {
+		total += p.Flow
+	}
+	if total != 15 || len(cycles) != 0 {
+		fmt.Println("flow - want: 15 got:", total, "cycles:", cycles)
+		t.Fatal()
+	}
+}
+
+func TestSTBridges(t *testing.T) {
+	// 1->2 and 5->6 carry all of the flow; 1->2 is also the min cut
+	data := `p max 6 6
+n 1 s
+n 6 t
+a 1 2 10
+a 2 3 5
+a 2 4 5
+a 3 5 5
+a 4 5 5
+a 5 6 20
+`
+	s := NewSession(Context{})
+	if s.STBridges() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	bridges := s.STBridges()
+	want := []A{{From: 1, To: 2, Capacity: 10, Flow: 10}, {From: 5, To: 6, Capacity: 20, Flow: 10}}
+	if !reflect.DeepEqual(bridges, want) {
+		fmt.Println("want:", want, "got:", bridges)
+		t.Fatal()
+	}
+
+	// no single arc carries all of the flow
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if bridges = s.STBridges(); len(bridges) != 0 {
+		fmt.Println("want: none got:", bridges)
+		t.Fatal()
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	// paths 1-2-4 and 1-3-4; 3->5->6->3 is a cycle with spare capacity
+	data := `p max 6 7
+n 1 s
+n 4 t
+a 1 2 5
+a 1 3 5
+a 2 4 5
+a 3 4 5
+a 3 5 10
+a 5 6 10
+a 6 3 10
+`
+	s := NewSession(Context{})
+	if _, _, err := s.Decompose(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	// add a circulation; the flow is still feasible and maximal
+	for _, a := range s.arcList {
+		switch [2]uint{a.from.number, a.to.number} {
+		case [2]uint{3, 5}, [2]uint{5, 6}, [2]uint{6, 3}:
+			a.flow += 3
+		}
+	}
+
+	paths, cycles, err := s.Decompose()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxFlow, _ := s.MaxFlow()
+	used := make(map[[2]uint]int)
+	var total int
+	for _, p := range paths {
+		if p.Nodes[0] != 1 || p.Nodes[len(p.Nodes)-1] != 4 || p.Flow <= 0 {
+			fmt.Println("bad path:", p)
+			t.Fatal()
+		}
+		for i := 1; i < len(p.Nodes); i++ {
+			used[[2]uint{p.Nodes[i-1], p.Nodes[i]}] += p.Flow
+		}
+		total += p.Flow
+	}
+	if total != int(maxFlow) || total != 10 {
+		fmt.Println("path flow - want:", maxFlow, "got:", total)
+		t.Fatal()
+	}
+	if len(cycles) != 1 || len(cycles[0].Nodes) != 3 || cycles[0].Flow != 3 {
+		fmt.Println("want: one cycle of 3 nodes, flow 3 got:", cycles)
+		t.Fatal()
+	}
+	for _, c := range cycles {
+		for i := range c.Nodes {
+			used[[2]uint{c.Nodes[i], c.Nodes[(i+1)%len(c.Nodes)]}] += c.Flow
+		}
+	}
+
+	// paths and cycles account for the flow on every arc
+	for _, a := range s.Flows() {
+		k := [2]uint{a.From, a.To}
+		if used[k] != a.Flow {
+			fmt.Println("arc", k, "flow - want:", a.Flow, "got:", used[k])
+			t.Fatal()
+		}
+		delete(used, k)
+	}
+	if len(used) != 0 {
+		fmt.Println("paths use arcs without flow:", used)
+		t.Fatal()
+	}
+}
+
+func TestFlowsByLayer(t *testing.T) {
+	s := NewSession(Context{})
+	if s.FlowsByLayer() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 2->5 carries no flow
+	want := map[uint][]A{
+		0: {{1, 2, 5, 5}, {1, 3, 15, 10}},
+		1: {{3, 4, 5, 5}, {3, 5, 5, 5}, {2, 4, 5, 5}},
+		2: {{5, 6, 5, 5}, {4, 6, 15, 10}},
+	}
+	if got := s.FlowsByLayer(); !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+}
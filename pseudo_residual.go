@@ -0,0 +1,130 @@
+// pseudo_residual.go - reachability in the residual graph of a solution.
+
+package pseudo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// residualReach returns, indexed by node number - 1, the nodes that can be
+// reached from node 'start' along arcs of the residual graph or, if 'reverse'
+// is set, the nodes from which 'start' can be reached. A residual arc u->v
+// exists if arc (u, v) has flow less than its capacity or arc (v, u) has flow
+// more than its minimum.
+func (s *Session) residualReach(start uint, reverse bool) []bool {
+	incident := make([][]*arc, s.numNodes)
+	for i := uint(0); i < s.numArcs; i++ {
+		a := s.arcList[i]
+		if a.from == a.to {
+			continue
+		}
+		incident[a.from.number-1] = append(incident[a.from.number-1], a)
+		incident[a.to.number-1] = append(incident[a.to.number-1], a)
+	}
+
+	seen := make([]bool, s.numNodes)
+	if start == 0 || start > s.numNodes {
+		return seen
+	}
+	seen[start-1] = true
+	queue := []uint{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, a := range incident[n-1] {
+			next, along := a.to.number, a.from.number == n
+			if !along {
+				next = a.from.number
+			}
+			if reverse {
+				along = !along
+			}
+			// along: the residual arc is in the direction of 'a', so it needs spare capacity
+			if (along && a.flow >= a.capacity) || (!along && a.flow <= a.lower) {
+				continue
+			}
+			if !seen[next-1] {
+				seen[next-1] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+// CutSensitivity returns, for each arc of the minimum cut keyed by its
+// {from, to} node numbers, how much the max flow increases for a unit of
+// capacity added to that arc alone. It is 1 if, in the residual graph, the
+// source can reach the arc's from node and its to node can reach the sink,
+// since the added unit then completes an augmenting path; otherwise it is 0
+// because another arc of the cut, or elsewhere, still limits the flow. Parallel
+// arcs share a key. It returns nil if the Session has not processed any data.
+func (s *Session) CutSensitivity() map[[2]uint]int {
+	if !s.solved {
+		return nil
+	}
+
+	set := s.sourceSet()
+	fromSource := s.residualReach(s.source, false)
+	toSink := s.residualReach(s.sink, true)
+	sens := make(map[[2]uint]int)
+	for i := uint(0); i < s.numArcs; i++ {
+		a := s.arcList[i]
+		if !set[a.from.number-1] || set[a.to.number-1] {
+			continue
+		}
+		k := [2]uint{a.from.number, a.to.number}
+		if fromSource[a.from.number-1] && toSink[a.to.number-1] {
+			sens[k] = 1
+		} else if _, ok := sens[k]; !ok {
+			sens[k] = 0
+		}
+	}
+	return sens
+}
+
+// MinCutIsUnique reports whether the graph has only one minimum cut. The
+// nodes the source can reach in the residual graph of a max flow are the
+// smallest source set of any minimum cut, and the nodes that can't reach
+// the sink are the largest; see Context.SinkMinimalCut. Every minimum cut
+// lies between the two, so the cut is unique exactly when they coincide.
+// It returns ErrNoSolution if the Session has not processed any data.
+func (s *Session) MinCutIsUnique() (bool, error) {
+	if !s.solved {
+		return false, ErrNoSolution
+	}
+
+	fromSource := s.residualReach(s.source, false)
+	toSink := s.residualReach(s.sink, true)
+	for i := range fromSource {
+		if fromSource[i] == toSink[i] {
+			// in the smallest source set but not the largest, or the reverse
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// crossCheckMinCut recomputes the min cut of the solution independently of
+// the node labels, as the capacity of the cut whose source set is the nodes
+// the source can reach in the residual graph, and returns it. It returns an
+// error if the sink is reachable - the flow is not a max flow - or if the
+// value differs from that of the cut the Session reports, which would be a
+// bug in the label handling. It is for verification in tests; it is not
+// fast.
+func (s *Session) crossCheckMinCut() (int, error) {
+	if !s.solved {
+		return 0, ErrNoSolution
+	}
+
+	set := s.residualReach(s.source, false)
+	if set[s.sink-1] {
+		return 0, errors.New("sink is reachable in the residual graph - flow is not a max flow")
+	}
+	cut := s.cutCapacity(set)
+	if mc := s.minCut(); cut != mc {
+		return cut, fmt.Errorf("residual graph min cut %d differs from label min cut %d", cut, mc)
+	}
+	return cut, nil
+}
@@ -0,0 +1,83 @@
+// pseudo_sort_test.go - quickSort tests.
+
+package pseudo
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// quickSortRecursive is quickSort recursing as in the C source.
+func quickSortRecursive(arr []*arc, first, last uint) {
+	if (last - first) <= 5 {
+		bubbleSort(arr, first, last)
+		return
+	}
+	left := partition(arr, first, last)
+	if left > first+1 {
+		quickSortRecursive(arr, first, left-1)
+	}
+	if left+1 < last {
+		quickSortRecursive(arr, left+1, last)
+	}
+}
+
+// sortArcs returns 'n' arcs with flows from 'flow'.
+func sortArcs(n int, flow func(i int) int) []*arc {
+	arcs := make([]*arc, n)
+	for i := range arcs {
+		arcs[i] = &arc{flow: flow(i)}
+	}
+	return arcs
+}
+
+func TestQuickSort(t *testing.T) {
+	const n = 20000
+	r := rand.New(rand.NewSource(1))
+	for _, v := range []struct {
+		name string
+		flow func(i int) int
+	}{
+		{"ascending", func(i int) int { return i }},
+		{"descending", func(i int) int { return n - i }},
+		{"equal", func(i int) int { return 7 }},
+		{"few values", func(i int) int { return r.Intn(3) }},
+		{"random", func(i int) int { return r.Intn(n) }},
+		{"small", func(i int) int { return i % 4 }},
+	} {
+		arcs := sortArcs(n, v.flow)
+		if v.name == "small" {
+			arcs = arcs[:6]
+		}
+		want := append([]*arc{}, arcs...)
+		quickSortRecursive(want, 0, uint(len(want)-1))
+
+		// sort the outOfTree arcs of a node with many of them
+		nd := &node{outOfTree: arcs, numberOutOfTree: uint(len(arcs))}
+		nd.sort()
+		for i := range want {
+			if arcs[i] != want[i] {
+				fmt.Println(v.name, "- order differs from the recursive sort at", i)
+				t.Fatal()
+			}
+		}
+		for i := 1; i < len(arcs); i++ {
+			if arcs[i].flow > arcs[i-1].flow {
+				fmt.Println(v.name, "- not in descending order at", i)
+				t.Fatal()
+			}
+		}
+	}
+}
+
+func BenchmarkQuickSort(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	arcs := sortArcs(50000, func(i int) int { return r.Intn(1000) })
+	work := make([]*arc, len(arcs))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		copy(work, arcs)
+		quickSort(work, 0, uint(len(work)-1))
+	}
+}
@@ -0,0 +1,309 @@
+// pseudo_state.go - checkpointing the solver state.
+
+package pseudo
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// gobState is the Session as written by SaveState. Node and arc pointers
+// are saved as node numbers and 1-based arc indexes, 0 for nil. The arcs
+// are arcList followed by the parts of the arcs split by SetInitialFlow.
+type gobState struct {
+	Ctx                                   Context
+	Solved, Loaded, Interrupted           bool
+	MaxFlow                               int
+	LowestStrongLabel, HighestStrongLabel uint
+	NumNodes, NumArcs, Source, Sink       uint
+	InputNodes                            uint // see Context.MultiTerminal
+	LabelCount                            []uint
+	Stats                                 Stats
+	Nodes                                 []gobNode
+	Arcs                                  []gobArc
+	Splits                                []uint // arc index of each split arc
+	Roots                                 []gobRoot
+	InitialFlow                           []A
+}
+
+type gobNode struct {
+	ArcToParent                          uint
+	ChildList, Next, NextScan, Parent    uint
+	Excess                               int
+	Label, NextArc, NumAdjacent, Visited uint
+	NumberOutOfTree                      uint
+	OutOfTree                            []uint
+}
+
+type gobArc struct {
+	From, To              uint
+	Flow, Capacity, Lower int
+	Direction             uint
+}
+
+type gobRoot struct {
+	Start, End, Size uint
+}
+
+// SaveState writes the Session - the loaded graph and the state of the
+// solver - to 'w' using gob encoding. A solve that was cancelled, e.g., with
+// RunNAWriterContext, can then be resumed by another process: LoadState
+// restores the Session and RunWriter continues the solve. A loaded graph
+// that is not yet solved, or a solution, can be saved as well.
+// Context.WarnWriter is not saved.
+func (s *Session) SaveState(w io.Writer) error {
+	arcIndex := make(map[*arc]uint, s.numArcs+uint(len(s.splitArcs)))
+	arcs := make([]*arc, 0, s.numArcs+uint(len(s.splitArcs)))
+	for i := uint(0); i < s.numArcs; i++ {
+		arcs = append(arcs, s.arcList[i])
+	}
+	for _, v := range s.splitArcs {
+		arcs = append(arcs, v.part)
+	}
+	for i, a := range arcs {
+		arcIndex[a] = uint(i + 1)
+	}
+	num := func(n *node) uint {
+		if n == nil {
+			return 0
+		}
+		return n.number
+	}
+
+	st := gobState{
+		Ctx:                s.ctx,
+		Solved:             s.solved,
+		Loaded:             s.loaded,
+		Interrupted:        s.interrupted,
+		MaxFlow:            s.maxFlow,
+		LowestStrongLabel:  s.lowestStrongLabel,
+		HighestStrongLabel: s.highestStrongLabel,
+		NumNodes:           s.numNodes,
+		NumArcs:            s.numArcs,
+		Source:             s.source,
+		Sink:               s.sink,
+		InputNodes:         s.inputNodes,
+		LabelCount:         s.labelCount,
+		Stats:              s.stats,
+		Nodes:              make([]gobNode, s.numNodes),
+		Arcs:               make([]gobArc, len(arcs)),
+		Splits:             make([]uint, len(s.splitArcs)),
+		Roots:              make([]gobRoot, len(s.strongRoots)),
+		InitialFlow:        s.initialFlow,
+	}
+	st.Ctx.WarnWriter = nil
+	for i, n := range s.adjacencyList {
+		gn := gobNode{
+			ArcToParent:     arcIndex[n.arcToParent],
+			ChildList:       num(n.childList),
+			Next:            num(n.next),
+			NextScan:        num(n.nextScan),
+			Parent:          num(n.parent),
+			Excess:          n.excess,
+			Label:           n.label,
+			NextArc:         n.nextArc,
+			NumAdjacent:     n.numAdjacent,
+			Visited:         n.visited,
+			NumberOutOfTree: n.numberOutOfTree,
+			OutOfTree:       make([]uint, len(n.outOfTree)),
+		}
+		for j, a := range n.outOfTree {
+			gn.OutOfTree[j] = arcIndex[a]
+		}
+		st.Nodes[i] = gn
+	}
+	for i, a := range arcs {
+		st.Arcs[i] = gobArc{a.from.number, a.to.number, a.flow, a.capacity, a.lower, a.direction}
+	}
+	for i, v := range s.splitArcs {
+		st.Splits[i] = arcIndex[v.orig]
+	}
+	for i, r := range s.strongRoots {
+		st.Roots[i] = gobRoot{num(r.start), num(r.end), r.size}
+	}
+
+	return gob.NewEncoder(w).Encode(st)
+}
+
+// LoadState returns the Session saved by SaveState.
+func LoadState(r io.Reader) (*Session, error) {
+	var st gobState
+	if err := gob.NewDecoder(r).Decode(&st); err != nil {
+		return nil, err
+	}
+	if err := st.check(); err != nil {
+		return nil, err
+	}
+
+	s := NewSession(st.Ctx)
+	s.solved, s.loaded, s.interrupted = st.Solved, st.Loaded, st.Interrupted
+	s.maxFlow = st.MaxFlow
+	s.lowestStrongLabel, s.highestStrongLabel = st.LowestStrongLabel, st.HighestStrongLabel
+	s.numNodes, s.numArcs, s.source, s.sink = st.NumNodes, st.NumArcs, st.Source, st.Sink
+	s.inputNodes = st.InputNodes
+	s.labelCount = st.LabelCount
+	if s.labelCount == nil {
+		s.labelCount = make([]uint, s.numNodes)
+	}
+	s.stats = st.Stats
+	s.initialFlow = st.InitialFlow
+
+	s.adjacencyList = make([]*node, s.numNodes)
+	for i := range s.adjacencyList {
+		s.adjacencyList[i] = s.newNode(uint(i + 1))
+	}
+	nodeAt := func(n uint) *node {
+		if n == 0 {
+			return nil
+		}
+		return s.adjacencyList[n-1]
+	}
+	arcs := make([]*arc, len(st.Arcs))
+	for i, a := range st.Arcs {
+		arcs[i] = &arc{from: nodeAt(a.From), to: nodeAt(a.To), flow: a.Flow, capacity: a.Capacity, lower: a.Lower, direction: a.Direction}
+	}
+	arcAt := func(i uint) *arc {
+		if i == 0 {
+			return nil
+		}
+		return arcs[i-1]
+	}
+	s.arcList = arcs[:s.numArcs:s.numArcs]
+	for i, v := range st.Splits {
+		s.splitArcs = append(s.splitArcs, splitArc{arcAt(v), arcs[s.numArcs+uint(i)]})
+	}
+
+	for i, gn := range st.Nodes {
+		n := s.adjacencyList[i]
+		n.arcToParent = arcAt(gn.ArcToParent)
+		n.childList = nodeAt(gn.ChildList)
+		n.next = nodeAt(gn.Next)
+		n.nextScan = nodeAt(gn.NextScan)
+		n.parent = nodeAt(gn.Parent)
+		n.excess = gn.Excess
+		n.label = gn.Label
+		n.nextArc = gn.NextArc
+		n.numAdjacent = gn.NumAdjacent
+		n.visited = gn.Visited
+		n.numberOutOfTree = gn.NumberOutOfTree
+		n.outOfTree = make([]*arc, len(gn.OutOfTree))
+		for j, a := range gn.OutOfTree {
+			n.outOfTree[j] = arcAt(a)
+		}
+	}
+
+	s.strongRoots = make([]*root, len(st.Roots))
+	for i, r := range st.Roots {
+		s.strongRoots[i] = &root{start: nodeAt(r.Start), end: nodeAt(r.End), size: r.Size}
+	}
+
+	return s, nil
+}
+
+// check validates the node and arc references of a decoded state so that
+// a corrupt state is rejected rather than causing a panic.
+func (st *gobState) check() error {
+	nn, na := st.NumNodes, uint(len(st.Arcs))
+	bad := func(what string, v, max uint) error {
+		if v > max {
+			return fmt.Errorf("corrupt state: %s %d out of range 0-%d", what, v, max)
+		}
+		return nil
+	}
+
+	if uint(len(st.Nodes)) != nn || na != st.NumArcs+uint(len(st.Splits)) {
+		return fmt.Errorf("corrupt state: %d nodes and %d arcs for %d nodes and %d arcs", len(st.Nodes), na, nn, st.NumArcs)
+	}
+	if (st.LabelCount != nil && uint(len(st.LabelCount)) != nn) || (st.Roots != nil && uint(len(st.Roots)) != nn) {
+		return fmt.Errorf("corrupt state: label counts or strong roots don't match %d nodes", nn)
+	}
+	if st.InputNodes > nn {
+		return fmt.Errorf("corrupt state: %d input nodes for %d nodes", st.InputNodes, nn)
+	}
+	if nn > 0 && (st.Source < 1 || st.Source > nn || st.Sink < 1 || st.Sink > nn) {
+		return fmt.Errorf("corrupt state: source %d or sink %d out of range 1-%d", st.Source, st.Sink, nn)
+	}
+	for _, v := range st.Arcs {
+		if v.From < 1 || v.From > nn || v.To < 1 || v.To > nn {
+			return fmt.Errorf("corrupt state: arc (%d, %d) out of range 1-%d", v.From, v.To, nn)
+		}
+	}
+	for _, v := range st.Splits {
+		if v < 1 || v > st.NumArcs {
+			return fmt.Errorf("corrupt state: split arc %d out of range 1-%d", v, st.NumArcs)
+		}
+	}
+	for _, n := range st.Nodes {
+		for _, err := range []error{
+			bad("arc", n.ArcToParent, na),
+			bad("node", n.ChildList, nn),
+			bad("node", n.Next, nn),
+			bad("node", n.NextScan, nn),
+			bad("node", n.Parent, nn),
+			bad("node label", n.Label, nn),
+		} {
+			if err != nil {
+				return err
+			}
+		}
+		if n.NumberOutOfTree > uint(len(n.OutOfTree)) {
+			return fmt.Errorf("corrupt state: %d out-of-tree arcs in a list of %d", n.NumberOutOfTree, len(n.OutOfTree))
+		}
+		for _, a := range n.OutOfTree {
+			if err := bad("arc", a, na); err != nil {
+				return err
+			}
+		}
+	}
+	for _, r := range st.Roots {
+		if err := bad("node", r.Start, nn); err != nil {
+			return err
+		}
+		if err := bad("node", r.End, nn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloneWithContext returns a copy of the graph of the Session, loaded or
+// solved, in a new Session with Context 'c', so the same input can be parsed
+// once and solved with, say, LowestLabel or FifoBuckets set and not. The
+// copy is rebuilt from the arcs, in load order, with their capacities and
+// no flow; it is independent of the Session and is solved with RunWriter.
+// The Session is not changed. It returns ErrNoGraph if the Session has no
+// graph.
+func (s *Session) CloneWithContext(c Context) (*Session, error) {
+	if !s.loaded && !s.solved && !s.interrupted && !s.updated {
+		return nil, ErrNoGraph
+	}
+
+	// the parts of split arcs are capacity of the original arcs
+	split := make(map[*arc]int, len(s.splitArcs))
+	for _, v := range s.splitArcs {
+		split[v.orig] += v.part.capacity
+	}
+
+	// load with the default Context so that, e.g., MergeParallelArcs in 'c'
+	// doesn't change the arcs
+	clone := NewSession(Context{})
+	si := NewSessionInitializer(clone)
+	si.Init(s.numNodes, s.numArcs)
+	si.SetSource(s.source)
+	si.SetSink(s.sink)
+	for _, a := range s.loadOrder() {
+		si.addArc(a.from.number, a.to.number, a.capacity+split[a], a.lower)
+	}
+	if err := si.Complete(); err != nil {
+		return nil, err
+	}
+	clone.inputNodes = s.inputNodes
+	if s.initialFlow != nil {
+		clone.initialFlow = append([]A(nil), s.initialFlow...)
+	}
+	if err := clone.SetContext(c); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
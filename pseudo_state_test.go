@@ -0,0 +1,141 @@
+// pseudo_state_test.go - SaveState and LoadState tests.
+
+package pseudo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// countdownCtx is done after Err has been called 'n' times.
+type countdownCtx struct {
+	context.Context
+	n int
+}
+
+func (c *countdownCtx) Err() error {
+	if c.n--; c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestSaveState(t *testing.T) {
+	defer func(v uint) { ctxCheckInterval = v }(ctxCheckInterval)
+	ctxCheckInterval = 1
+
+	for _, file := range []string{"_data/dimacsMaxf.txt", "_data/BVZ-tsukuba0.max"} {
+		fh, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+		fh.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want bytes.Buffer
+		if err = NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, &want); err != nil {
+			t.Fatal(err)
+		}
+
+		// interrupt after a few strong roots, save and resume elsewhere
+		s := NewSession(Context{})
+		var got bytes.Buffer
+		ctx := &countdownCtx{context.Background(), 3}
+		if err = s.RunNAWriterContext(ctx, numNodes, numArcs, n, a, &got); err != context.Canceled {
+			fmt.Println(file, "want:", context.Canceled, "got:", err)
+			t.Fatal()
+		}
+		var state bytes.Buffer
+		if err = s.SaveState(&state); err != nil {
+			t.Fatal(err)
+		}
+		s, err = LoadState(&state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = s.RunWriter(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != want.String() {
+			fmt.Println(file, "resumed solve differs - want:\n", want.String())
+			fmt.Println("got:\n", got.String())
+			t.Fatal()
+		}
+		if err = s.RunWriter(&got); err != ErrNoGraph {
+			fmt.Println(file, "want:", ErrNoGraph, "got:", err)
+			t.Fatal()
+		}
+	}
+
+	if _, err := LoadState(bytes.NewReader([]byte("not a state"))); err == nil {
+		fmt.Println("want: decode error")
+		t.Fatal()
+	}
+}
+
+func TestCloneWithContext(t *testing.T) {
+	var data bytes.Buffer
+	if err := GenerateRandomDimacs(&data, 500, 3000, 100, 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range [][]byte{nil, data.Bytes()} {
+		if in == nil {
+			var err error
+			if in, err = os.ReadFile("_data/dimacsMaxf.txt"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		s := NewSession(Context{})
+		if _, err := s.CloneWithContext(Context{}); err != ErrNoGraph {
+			fmt.Println("want:", ErrNoGraph, "got:", err)
+			t.Fatal()
+		}
+		// parse the DIMACS input once
+		var buf bytes.Buffer
+		if err := s.RunReadWriter(ioutil.NopCloser(bytes.NewReader(in)), &buf); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := s.MaxFlow()
+		flows := s.Flows()
+
+		for _, c := range []Context{{FifoBuckets: true}, {LowestLabel: true}, {LowestLabel: true, FifoBuckets: true}, {MergeParallelArcs: true}} {
+			clone, err := s.CloneWithContext(c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clone.ConfigJSON() != NewSession(c).ConfigJSON() {
+				fmt.Println("want:", NewSession(c).ConfigJSON(), "got:", clone.ConfigJSON())
+				t.Fatal()
+			}
+			if clone.NumArcs() != s.NumArcs() {
+				fmt.Println("arcs - want:", s.NumArcs(), "got:", clone.NumArcs())
+				t.Fatal()
+			}
+			if err = clone.RunWriter(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if mf, _ := clone.MaxFlow(); mf != want {
+				fmt.Println(clone.ConfigJSON(), "want:", want, "got:", mf)
+				t.Fatal()
+			}
+			if _, err = clone.crossCheckMinCut(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// the Session is unchanged
+		if !reflect.DeepEqual(s.Flows(), flows) {
+			fmt.Println("flows of the Session changed")
+			t.Fatal()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// pseudo_strict.go - solving with all input validation enabled.
+
+package pseudo
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The kinds of InputError.
+var (
+	ErrMultipleProblems = errors.New("multiple 'p' lines")
+	ErrNodeRange        = errors.New("node out of range")
+	ErrSelfLoop         = errors.New("arc from a node to itself")
+	ErrArcCount         = errors.New("number of arcs doesn't match the 'p' line")
+	ErrTerminals        = errors.New("invalid source or sink")
+	ErrSyntax           = errors.New("malformed line")
+)
+
+// InputError is an error in DIMACS input found by validation. Err is its
+// kind, one of the ErrMultipleProblems, ErrNodeRange, ErrSelfLoop, ErrArcCount,
+// ErrTerminals and ErrSyntax values, so errors.Is can be used to test for it.
+// Malformed lines are reported as *ParseError values.
+type InputError struct {
+	Line uint // the input line, 0 if the error isn't on one line
+	Err  error
+	msg  string
+}
+
+func (e *InputError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.msg)
+	}
+	return e.msg
+}
+
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+func inputError(line uint, kind error, format string, a ...interface{}) error {
+	return &InputError{Line: line, Err: kind, msg: fmt.Sprintf(format, a...)}
+}
+
+// ParseError is a DIMACS input line that can't be parsed. Kind is the type
+// of the line - "p", "a" or "n" - or "order" for an 'a' or 'n' line before
+// the 'p' line and "unknown" for a line of another type. Raw is the line and
+// Err the reason. errors.Is reports a ParseError as ErrSyntax.
+type ParseError struct {
+	Line uint
+	Kind string
+	Raw  string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Is(target error) bool {
+	return target == ErrSyntax
+}
+
+func parseError(line uint, kind string, raw []byte, err error) error {
+	return &ParseError{Line: line, Kind: kind, Raw: string(raw), Err: err}
+}
+
+// RunStrict is RunResult with all input validation enabled. In addition to
+// the usual checks it rejects input with more than one 'p' line, arcs from a
+// node to itself and a source that is the sink; these errors, like those for
+// a missing or repeated source or sink, node numbers out of range or a number of 'a'
+// lines that differs from the 'p' line, are *InputError values. For the call, Context.SkipBadLines is
+// ignored and Context.StrictFeasibility is set, so an infeasible solution
+// returns ErrInfeasibleSolution.
+func (s *Session) RunStrict(r io.Reader) (*Result, error) {
+	ctx := s.ctx
+	s.ctx.SkipBadLines, s.ctx.StrictFeasibility = false, true
+	s.strict = true
+	defer func() {
+		s.ctx, s.strict = ctx, false
+	}()
+
+	return s.RunResult(r, "")
+}
+
+// ValidateDimacs reads DIMACS max flow input from 'r' and checks it as
+// RunStrict does - one 'p' line before any 'a' or 'n' line, node numbers in
+// range, no self-loops, as many 'a' lines as declared, and a source and a
+// sink that are different nodes - without loading or solving the graph; the
+// input is read a line at a time and only the 'p' line values are kept. It
+// returns the first error found: an *InputError, or a *ParseError for a
+// malformed line. Lines are numbered as in Run errors.
+func ValidateDimacs(r io.Reader) error {
+	s := NewSession(Context{})
+	s.strict, s.validate = true, true
+	return s.readDimacsFile(r)
+}
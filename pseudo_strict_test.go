@@ -0,0 +1,161 @@
+// pseudo_strict_test.go - RunStrict tests.
+
+package pseudo
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRunStrict(t *testing.T) {
+	s := NewSession(Context{SkipBadLines: true})
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.RunStrict(fh)
+	fh.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MaxFlow != 15 {
+		fmt.Println("want: 15 got:", res.MaxFlow)
+		t.Fatal()
+	}
+	if c := s.ConfigJSON(); !strings.Contains(c, `"SkipBadLines":true`) || !strings.Contains(c, `"StrictFeasibility":false`) {
+		fmt.Println("Context not restored:", c)
+		t.Fatal()
+	}
+
+	for _, v := range []struct {
+		data string
+		kind error
+		line uint
+	}{
+		{"p max 3 2\nn 1 s\nn 3 t\np max 3 2\na 1 2 5\na 2 3 5\n", ErrMultipleProblems, 4},
+		{"p max 3 2\nn 1 s\nn 3 t\na 1 2 5\na 2 4 5\n", ErrNodeRange, 5},
+		{"p max 3 2\nn 1 s\nn 4 t\na 1 2 5\na 2 3 5\n", ErrNodeRange, 3},
+		{"p max 3 2 1 9\na 1 2 5\na 2 3 5\n", ErrNodeRange, 0},
+		{"p max 3 3\nn 1 s\nn 3 t\na 1 2 5\na 2 2 5\na 2 3 5\n", ErrSelfLoop, 5},
+		{"p max 3 3\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", ErrArcCount, 0},
+		{"p max 3 1\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", ErrArcCount, 0},
+		{"p max 3 2\nn 1 s\na 1 2 5\na 2 3 5\n", ErrTerminals, 0},
+		{"p max 3 2\nn 1 s\nn 1 t\na 1 2 5\na 2 3 5\n", ErrTerminals, 0},
+	} {
+		_, err := s.RunStrict(strings.NewReader(v.data))
+		var ie *InputError
+		if !errors.Is(err, v.kind) || !errors.As(err, &ie) || ie.Line != v.line {
+			fmt.Printf("%q want: %v on line %d got: %v\n", v.data, v.kind, v.line, err)
+			t.Fatal()
+		}
+	}
+
+	// malformed lines are not skipped
+	if _, err = s.RunStrict(strings.NewReader("p max 3 2\nn 1 s\nn 3 t\na 1 2\na 2 3 5\n")); err == nil {
+		fmt.Println("want: error for malformed line")
+		t.Fatal()
+	}
+}
+
+func TestValidateDimacs(t *testing.T) {
+	for _, file := range []string{"_data/dimacsMaxf.txt", "_data/BVZ-tsukuba0.max"} {
+		fh, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ValidateDimacs(fh)
+		fh.Close()
+		if err != nil {
+			fmt.Println(file, err)
+			t.Fatal()
+		}
+	}
+
+	for _, v := range []struct {
+		data string
+		kind error
+		line uint
+	}{
+		{"p max 3 2\nn 1 s\nn 3 t\np max 3 2\na 1 2 5\na 2 3 5\n", ErrMultipleProblems, 4},
+		{"p max 3 2\nn 1 s\nn 3 t\na 1 2 5\na 2 4 5\n", ErrNodeRange, 5},
+		{"p max 3 2\nn 1 s\nn 4 t\na 1 2 5\na 2 3 5\n", ErrNodeRange, 3},
+		{"p max 3 2 1 9\na 1 2 5\na 2 3 5\n", ErrNodeRange, 0},
+		{"p max 3 3\nn 1 s\nn 3 t\na 1 2 5\na 2 2 5\na 2 3 5\n", ErrSelfLoop, 5},
+		{"p max 3 3\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", ErrArcCount, 0},
+		{"p max 3 1\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", ErrArcCount, 0},
+		{"p max 3 2\nn 1 s\na 1 2 5\na 2 3 5\n", ErrTerminals, 0},
+		{"p max 3 2\nn 1 s\nn 1 t\na 1 2 5\na 2 3 5\n", ErrTerminals, 0},
+		{"p max 3 2\nn 1 s\nn 2 s\nn 3 t\na 1 2 5\na 2 3 5\n", ErrTerminals, 3},
+		{"n 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", ErrSyntax, 1},
+		{"c no graph\n", ErrSyntax, 0},
+		{"p max 3 2\nn 1 s\nn 3 t\na 1 2\na 2 3 5\n", ErrSyntax, 4},
+		{"p max 3 2\nn 1 s\nn 3 x\na 1 2 5\na 2 3 5\n", ErrSyntax, 3},
+		{"p max 3 2\nn 1 s\n\nn 3 t\nq\na 2 3 5", ErrSyntax, 5}, // the empty line is counted
+	} {
+		err := ValidateDimacs(strings.NewReader(v.data))
+		var line uint
+		var ie *InputError
+		var pe *ParseError
+		if errors.As(err, &ie) {
+			line = ie.Line
+		} else if errors.As(err, &pe) {
+			line = pe.Line
+		}
+		if !errors.Is(err, v.kind) || line != v.line {
+			fmt.Printf("%q want: %v on line %d got: %v\n", v.data, v.kind, v.line, err)
+			t.Fatal()
+		}
+
+		// RunStrict finds the same structural errors
+		if v.kind != ErrSyntax {
+			if _, rerr := NewSession(Context{}).RunStrict(strings.NewReader(v.data)); rerr == nil || !errors.Is(rerr, v.kind) {
+				fmt.Printf("%q ValidateDimacs: %v RunStrict: %v\n", v.data, err, rerr)
+				t.Fatal()
+			}
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	check := func(name string, err error, line uint, kind, raw string) {
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Line != line || pe.Kind != kind || pe.Raw != raw || !errors.Is(err, ErrSyntax) {
+			fmt.Printf("%s want: line %d %s %q got: %#v\n", name, line, kind, raw, err)
+			t.Fatal()
+		}
+	}
+
+	_, err := NewSession(Context{}).RunReader(ioutil.NopCloser(strings.NewReader(dimacsBadLines)))
+	check("RunReader", err, 6, "a", "a 2 x 5")
+	var ne *strconv.NumError
+	if !errors.As(err, &ne) {
+		fmt.Println("want: the strconv error got:", err)
+		t.Fatal()
+	}
+	_, _, _, _, err = ParseDimacsReader(strings.NewReader(dimacsBadLines))
+	check("ParseDimacsReader", err, 6, "a", "a 2 x 5")
+	err = ValidateDimacs(strings.NewReader(dimacsBadLines))
+	check("ValidateDimacs", err, 6, "a", "a 2 x 5")
+
+	for _, v := range []struct {
+		data, kind, raw string
+		line            uint
+	}{
+		{"p max 3\nn 1 s\n", "p", "p max 3", 1},
+		{"c x\na 1 2 5\np max 2 1\n", "order", "a 1 2 5", 2},
+		{"p max 2 1\nn 1\n", "n", "n 1", 2},
+		{"p max 2 1\nn 1 s\nn 2 x\na 1 2 5\n", "n", "n 2 x", 3},
+		{"p max 2 1\nn 1 s\nn 2 t\nz 1 2\n", "unknown", "z 1 2", 4},
+		{"p max 2 1\n\nn 1 s\n  \nn 2 t\nz 1 2\n", "unknown", "z 1 2", 6}, // empty lines are counted
+	} {
+		_, err = NewSession(Context{}).RunReader(ioutil.NopCloser(strings.NewReader(v.data)))
+		check(fmt.Sprintf("RunReader %q", v.data), err, v.line, v.kind, v.raw)
+	}
+	_, _, _, _, err = ParseDimacsReader(strings.NewReader("p max 2 1\n\nn 1 s\n  \nn 2 t\nz 1 2\n"))
+	check("ParseDimacsReader", err, 6, "unknown", "z 1 2")
+}
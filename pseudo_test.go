@@ -4,10 +4,18 @@
 package pseudo
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -202,3 +210,2197 @@ func TestRunJSON(t *testing.T) {
 	fmt.Println(string(results))
 }
 
+func TestStrictFeasibility(t *testing.T) {
+	s := NewSession(Context{StrictFeasibility: true})
+
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// inject a capacity violation
+	s.arcList[0].flow = s.arcList[0].capacity + 1
+	if err := s.checkFeasibility(); err != ErrInfeasibleSolution {
+		fmt.Println("want:", ErrInfeasibleSolution, "got:", err)
+		t.Fatal()
+	}
+
+	// default logging behavior reports the violation as a comment
+	var buf bytes.Buffer
+	if err := s.checkOptimality(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "c Capacity constraint violated on arc") {
+		fmt.Println("got:", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestFlowBalance(t *testing.T) {
+	s := NewSession(Context{})
+	if s.FlowBalance() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	balance := s.FlowBalance()
+	if len(balance) != 6 {
+		fmt.Println("len - want: 6 got:", len(balance))
+		t.Fatal()
+	}
+	for n, v := range balance {
+		want := 0
+		switch n {
+		case 1:
+			want = 15
+		case 6:
+			want = -15
+		}
+		if v != want {
+			fmt.Println("node", n, "- want:", want, "got:", v)
+			t.Fatal()
+		}
+	}
+}
+
+// StrictFeasibility is honored by process: a violation is an error with
+// the switch set and a comment without it.
+func TestStrictFeasibilityProcess(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// an arc into the source is never used by the solver
+	a = append(a, A{From: 3, To: 1, Capacity: 5})
+	numArcs++
+
+	for _, strict := range []bool{true, false} {
+		s := NewSession(Context{StrictFeasibility: strict})
+		if err = s.loadNA(numNodes, numArcs, n, a); err != nil {
+			t.Fatal(err)
+		}
+		// inject a violation: flow 0 exceeds the capacity
+		for _, v := range s.arcList {
+			if v.to.number == 1 {
+				v.capacity = -1
+			}
+		}
+
+		var buf bytes.Buffer
+		err = s.process(&buf)
+		if strict {
+			if err != ErrInfeasibleSolution {
+				fmt.Println("want:", ErrInfeasibleSolution, "got:", err)
+				t.Fatal()
+			}
+			if s.FlowBalance() != nil {
+				fmt.Println("rejected solution reported by FlowBalance")
+				t.Fatal()
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), "c Capacity constraint violated on arc (3, 1)") {
+			fmt.Println("got:", buf.String())
+			t.Fatal()
+		}
+	}
+}
+
+func TestEstimateMemory(t *testing.T) {
+	base := EstimateMemory(1000, 5000)
+	if base == 0 {
+		fmt.Println("estimate is 0")
+		t.Fatal()
+	}
+	if EstimateMemory(2000, 10000) != 2*base {
+		fmt.Println("want:", 2*base, "got:", EstimateMemory(2000, 10000))
+		t.Fatal()
+	}
+	// nodes and arcs contribute independently
+	if EstimateMemory(1000, 0)+EstimateMemory(0, 5000) != base {
+		fmt.Println("node and arc terms don't add up to:", base)
+		t.Fatal()
+	}
+}
+
+func TestPeakBucketSize(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if s.stats.PeakBucketSize < 1 {
+		fmt.Println("PeakBucketSize - want: >= 1 got:", s.stats.PeakBucketSize)
+		t.Fatal()
+	}
+	if !strings.Contains(s.StatsJSON(), `"peakBucketSize":`) {
+		fmt.Println("StatsJSON:", s.StatsJSON())
+		t.Fatal()
+	}
+	// all buckets are drained by the solve
+	for i, r := range s.strongRoots {
+		if r.size != 0 {
+			fmt.Println("bucket", i, "size:", r.size)
+			t.Fatal()
+		}
+	}
+}
+
+// source and sink on the 'p' line
+var dimacs6 = `p max 6 8 1 6
+a 1 2 5
+a 1 3 15
+a 2 4 5
+a 2 5 5
+a 3 4 5
+a 3 5 5
+a 4 6 15
+a 5 6 5
+`
+
+func TestSixFieldProblemLine(t *testing.T) {
+	s := NewSession(Context{})
+	results, err := s.RunReader(ioutil.NopCloser(strings.NewReader(dimacs6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.source != 1 || s.sink != 6 {
+		fmt.Println("source, sink - want: 1 6 got:", s.source, s.sink)
+		t.Fatal()
+	}
+	if !strings.Contains(strings.Join(results, "\n"), "\ns 15\n") {
+		fmt.Println("got:", results)
+		t.Fatal()
+	}
+
+	numNodes, numArcs, n, a, err := ParseDimacsReader(strings.NewReader(dimacs6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(n) != 2 || n[0] != (N{1, "s"}) || n[1] != (N{6, "t"}) {
+		fmt.Println("N - want: [{1 s} {6 t}] got:", n)
+		t.Fatal()
+	}
+	var buf bytes.Buffer
+	if err = NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\ns 15\n") {
+		fmt.Println("got:", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestTopFlows(t *testing.T) {
+	s := NewSession(Context{})
+	if s.TopFlows(3) != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// arcs (1, 3) and (4, 6) both carry 10
+	top := s.TopFlows(2)
+	if len(top) != 2 {
+		fmt.Println("len - want: 2 got:", len(top))
+		t.Fatal()
+	}
+	want := map[A]bool{
+		{From: 1, To: 3, Capacity: 15, Flow: 10}: true,
+		{From: 4, To: 6, Capacity: 15, Flow: 10}: true,
+	}
+	for _, v := range top {
+		if !want[v] {
+			fmt.Println("got:", top)
+			t.Fatal()
+		}
+		delete(want, v)
+	}
+
+	all := s.TopFlows(100)
+	if len(all) != 8 {
+		fmt.Println("len - want: 8 got:", len(all))
+		t.Fatal()
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Flow > all[i-1].Flow {
+			fmt.Println("not sorted:", all)
+			t.Fatal()
+		}
+	}
+	if all[7].Flow != 0 {
+		fmt.Println("smallest flow - want: 0 got:", all[7])
+		t.Fatal()
+	}
+}
+
+func TestTopFlowsGenerated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := GenerateRandomDimacs(&buf, 200, 1500, 50, 3); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession(Context{})
+	if err := s.RunReadWriter(ioutil.NopCloser(&buf), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	want := s.Flows()
+	sort.Slice(want, func(i, j int) bool {
+		if want[i].Flow != want[j].Flow {
+			return want[i].Flow > want[j].Flow
+		}
+		if want[i].From != want[j].From {
+			return want[i].From < want[j].From
+		}
+		return want[i].To < want[j].To
+	})
+	got := s.TopFlows(20)
+	// parallel arcs may be ordered either way
+	for i := range got {
+		if got[i].Flow != want[i].Flow || got[i].From != want[i].From || got[i].To != want[i].To {
+			fmt.Println("want:", want[:20])
+			fmt.Println("got: ", got)
+			t.Fatal()
+		}
+	}
+}
+
+func TestDeferredSessionInitializer(t *testing.T) {
+	s := NewSession(Context{})
+	si := NewDeferredSessionInitializer(s)
+	si.Init(3, 4)
+	si.SetSource(1)
+	si.SetSink(3)
+	si.AddArc(1, 2, 5)
+	si.AddArc(0, 2, 5)
+	si.AddArc(1, 5, 5)
+	si.AddArc(7, 8, 5)
+
+	err := si.Complete()
+	if err == nil {
+		fmt.Println("want: out of range error")
+		t.Fatal()
+	}
+	for _, v := range []string{"arc 2 (0, 2)", "arc 3 (1, 5)", "arc 4 (7, 8)"} {
+		if !strings.Contains(err.Error(), v) {
+			fmt.Println("want:", v, "got:", err)
+			t.Fatal()
+		}
+	}
+	if strings.Contains(err.Error(), "arc 1 ") {
+		fmt.Println("valid arc reported:", err)
+		t.Fatal()
+	}
+
+	// all valid
+	si = NewDeferredSessionInitializer(s)
+	si.Init(3, 2)
+	si.SetSource(1)
+	si.SetSink(3)
+	si.AddArc(1, 2, 5)
+	si.AddArc(2, 3, 4)
+	if err = si.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = s.process(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\ns 4\n") {
+		fmt.Println("got:", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestNegativeCapacity(t *testing.T) {
+	want := "negative capacity -5 on arc (1, 2)"
+	for _, deferred := range []bool{false, true} {
+		s := NewSession(Context{})
+		si := NewSessionInitializer(s)
+		if deferred {
+			si = NewDeferredSessionInitializer(s)
+		}
+		si.Init(3, 2)
+		si.SetSource(1)
+		si.SetSink(3)
+		si.AddArc(1, 2, -5)
+		si.AddArc(2, 3, 4)
+		if err := si.Complete(); err == nil || err.Error() != want {
+			fmt.Println("deferred:", deferred, "want:", want, "got:", err)
+			t.Fatal()
+		}
+		if err := s.RunWriter(ioutil.Discard); err != ErrNoGraph {
+			fmt.Println("want:", ErrNoGraph, "got:", err)
+			t.Fatal()
+		}
+	}
+
+	s := NewSession(Context{})
+	arcs := []A{{From: 1, To: 2, Capacity: -5}, {From: 2, To: 3, Capacity: 4}}
+	if err := s.RunNAWriter(3, 2, []N{{1, "s"}, {3, "t"}}, arcs, ioutil.Discard); err == nil || err.Error() != want {
+		fmt.Println("want:", want, "got:", err)
+		t.Fatal()
+	}
+}
+
+func TestWriteCutSets(t *testing.T) {
+	s := NewSession(Context{})
+	var src, sink bytes.Buffer
+	if err := s.WriteCutSets(&src, &sink); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteCutSets(&src, &sink); err != nil {
+		t.Fatal(err)
+	}
+	if src.String() != "n 1\nn 3\n" {
+		fmt.Println("source set - want: n 1, n 3 got:", src.String())
+		t.Fatal()
+	}
+	if sink.String() != "n 2\nn 4\nn 5\nn 6\n" {
+		fmt.Println("sink set - want: n 2, n 4, n 5, n 6 got:", sink.String())
+		t.Fatal()
+	}
+}
+
+// three malformed lines; the 'p' line counts the two bad arcs
+var dimacsBadLines = `p max 6 9
+n 1 s
+n 6 t
+a 1 2 5
+a 1 3 15
+a 2 x 5
+a 2 4 5
+a 2 5 5
+a 3 4 5
+? garbage
+a 3 5 5
+a 4 6 15
+a 5 6
+`
+
+func TestSkipBadLines(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(dimacsBadLines))); err == nil {
+		fmt.Println("want: parse error")
+		t.Fatal()
+	}
+
+	var warn bytes.Buffer
+	s = NewSession(Context{SkipBadLines: true, WarnWriter: &warn})
+	results, err := s.RunReader(ioutil.NopCloser(strings.NewReader(dimacsBadLines)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.stats.SkippedLines != 3 {
+		fmt.Println("SkippedLines - want: 3 got:", s.stats.SkippedLines)
+		t.Fatal()
+	}
+	if s.numArcs != 7 {
+		fmt.Println("numArcs - want: 7 got:", s.numArcs)
+		t.Fatal()
+	}
+	for _, v := range []string{"skipping line 6:", "skipping line 10:", "skipping line 13:"} {
+		if !strings.Contains(warn.String(), v) {
+			fmt.Println("want:", v, "got:", warn.String())
+			t.Fatal()
+		}
+	}
+	// without arc (5, 6) the max flow is 10
+	if !strings.Contains(strings.Join(results, "\n"), "c Solution checks as optimal") ||
+		!strings.Contains(strings.Join(results, "\n"), "\ns 10\n") {
+		fmt.Println("got:", results)
+		t.Fatal()
+	}
+}
+
+// every arc of the path is a min cut
+var dimacsTwoCuts = `p max 4 3
+n 1 s
+n 4 t
+a 1 2 5
+a 2 3 5
+a 3 4 5
+`
+
+func TestSinkMinimalCut(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(dimacsTwoCuts))); err != nil {
+		t.Fatal(err)
+	}
+	labelCut, labelValue := s.Cut(), s.minCut()
+
+	s = NewSession(Context{SinkMinimalCut: true})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(dimacsTwoCuts))); err != nil {
+		t.Fatal(err)
+	}
+	sinkCut, sinkValue := s.Cut(), s.minCut()
+
+	if fmt.Sprint(labelCut) != "[1]" || fmt.Sprint(sinkCut) != "[1 2 3]" {
+		fmt.Println("want: [1] [1 2 3] got:", labelCut, sinkCut)
+		t.Fatal()
+	}
+	if labelValue != 5 || sinkValue != 5 {
+		fmt.Println("cut value - want: 5 5 got:", labelValue, sinkValue)
+		t.Fatal()
+	}
+}
+
+func TestSourceSaturation(t *testing.T) {
+	s := NewSession(Context{})
+	if v := s.SourceSaturation(); v != 0 {
+		fmt.Println("want: 0 before Run got:", v)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// 15 of the 5 + 15 out of node 1
+	if v := s.SourceSaturation(); v != 0.75 {
+		fmt.Println("want: 0.75 got:", v)
+		t.Fatal()
+	}
+
+	// no arcs out of the source
+	s = NewSession(Context{})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader("p max 3 1\nn 1 s\nn 3 t\na 2 3 5\n"))); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.SourceSaturation(); v != 0 {
+		fmt.Println("want: 0 got:", v)
+		t.Fatal()
+	}
+}
+
+func TestCompareBucketStrategies(t *testing.T) {
+	for _, file := range []string{"_data/dimacsMaxf.txt", "_data/BVZ-tsukuba0.max"} {
+		fh, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fifo, lifo, err := CompareBucketStrategies(fh)
+		fh.Close()
+		if err != nil {
+			fmt.Println(file, err)
+			t.Fatal()
+		}
+		if fifo.Pushes == 0 || lifo.Pushes == 0 {
+			fmt.Println(file, "no pushes:", fifo, lifo)
+			t.Fatal()
+		}
+	}
+}
+
+func TestCutSensitivity(t *testing.T) {
+	s := NewSession(Context{})
+	if s.CutSensitivity() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	// only 3->4 has spare capacity on both sides: 1->3 and 4->6
+	want := map[[2]uint]int{{1, 2}: 0, {3, 4}: 1, {3, 5}: 0}
+	if got := s.CutSensitivity(); !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+}
+
+func TestIsConnected(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsConnected() {
+		fmt.Println("want: connected")
+		t.Fatal()
+	}
+
+	// 4-5 is connected to neither source nor sink
+	var warn bytes.Buffer
+	s = NewSession(Context{WarnWriter: &warn})
+	si := NewSessionInitializer(s)
+	si.Init(5, 3)
+	si.SetSource(1)
+	si.SetSink(3)
+	si.AddArc(1, 2, 5)
+	si.AddArc(2, 3, 5)
+	si.AddArc(4, 5, 5)
+	si.Complete()
+	if s.IsConnected() {
+		fmt.Println("want: not connected")
+		t.Fatal()
+	}
+	if warn.String() != "graph is not connected: 2 components\n" {
+		fmt.Println("warning:", warn.String())
+		t.Fatal()
+	}
+}
+
+func TestLabelStats(t *testing.T) {
+	for _, file := range []string{"_data/dimacsMaxf.txt", "_data/BVZ-tsukuba0.max"} {
+		s := NewSession(Context{})
+		if distinct, _ := s.LabelStats(); distinct != 0 {
+			fmt.Println("want: 0 before Run")
+			t.Fatal()
+		}
+		if _, err := s.Run(file); err != nil {
+			t.Fatal(err)
+		}
+		distinct, histogram := s.LabelStats()
+		var total uint
+		for _, v := range histogram {
+			total += v
+		}
+		if total != s.numNodes || distinct != uint(len(histogram)) || histogram[s.numNodes] == 0 {
+			fmt.Println(file, "numNodes:", s.numNodes, "got:", total, distinct, histogram[s.numNodes])
+			t.Fatal()
+		}
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	s := NewSession(Context{})
+	res, err := s.RunResult(fh, "sample graph")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Header != "sample graph" || res.MaxFlow != 15 || len(res.Flows) != 8 || fmt.Sprint(res.Cut) != "[1 3]" {
+		fmt.Println("got:", res.Header, res.MaxFlow, res.Flows, res.Cut)
+		t.Fatal()
+	}
+	var total int
+	for _, v := range res.Flows {
+		if v.From == 1 {
+			total += v.Flow
+		}
+	}
+	if total != 15 || res.Stats.Pushes == 0 || res.Timings.Total <= 0 {
+		fmt.Println("got:", total, res.Stats, res.Timings)
+		t.Fatal()
+	}
+}
+
+func TestMaxFlow(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.MaxFlow(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.MaxFlow(); err != nil || v != 15 {
+		fmt.Println("want: 15 got:", v, err)
+		t.Fatal()
+	}
+}
+
+func TestFlows(t *testing.T) {
+	s := NewSession(Context{})
+	if s.Flows() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+	results, err := s.Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// same values and order as the "f" lines
+	var lines []string
+	for _, v := range results {
+		if strings.HasPrefix(v, "f ") {
+			lines = append(lines, v)
+		}
+	}
+	flows := s.Flows()
+	if len(flows) != len(lines) {
+		fmt.Println("want:", len(lines), "got:", len(flows))
+		t.Fatal()
+	}
+	for i, v := range flows {
+		if f := fmt.Sprintf("f %d %d %d", v.From, v.To, v.Flow); f != lines[i] {
+			fmt.Println("want:", lines[i], "got:", f)
+			t.Fatal()
+		}
+	}
+}
+
+func TestSetContext(t *testing.T) {
+	s := NewSession(Context{})
+	highest, err := s.Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.SetContext(Context{LowestLabel: true}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Flows() != nil {
+		fmt.Println("solution not discarded")
+		t.Fatal()
+	}
+	lowest, err := s.Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if highest[7] != "c Highest label pseudoflow algorithm" || lowest[7] != "c Lowest label pseudoflow algorithm" {
+		fmt.Println("got:", highest[7], lowest[7])
+		t.Fatal()
+	}
+	for _, r := range [][]string{highest, lowest} {
+		if r[12] != "c Solution checks as optimal" || r[15] != "s 15" {
+			fmt.Println("got:\n", strings.Join(r, "\n"))
+			t.Fatal()
+		}
+	}
+
+	// not while a solve can be resumed
+	s.interrupted = true
+	if err = s.SetContext(Context{}); err == nil || !s.ctx.LowestLabel {
+		fmt.Println("want: error and unchanged Context got:", err, s.ConfigJSON())
+		t.Fatal()
+	}
+}
+
+func TestMinCutIsUnique(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.MinCutIsUnique(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	for _, v := range []struct {
+		data   string
+		unique bool
+	}{
+		{"p max 3 2\nn 1 s\nn 3 t\na 1 2 5\na 2 3 10\n", true},
+		{dimacsTwoCuts, false},
+	} {
+		s = NewSession(Context{})
+		if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(v.data))); err != nil {
+			t.Fatal(err)
+		}
+		unique, err := s.MinCutIsUnique()
+		if err != nil || unique != v.unique {
+			fmt.Println(v.data, "want:", v.unique, "got:", unique, err)
+			t.Fatal()
+		}
+	}
+}
+
+func TestSourceSinkSets(t *testing.T) {
+	s := NewSession(Context{})
+	if s.SourceSet() != nil || s.SinkSet() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+	// DisplayCut not set
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if src, sink := fmt.Sprint(s.SourceSet()), fmt.Sprint(s.SinkSet()); src != "[1 3]" || sink != "[2 4 5 6]" {
+		fmt.Println("want: [1 3] [2 4 5 6] got:", src, sink)
+		t.Fatal()
+	}
+}
+
+func TestReset(t *testing.T) {
+	for _, c := range []Context{{}, {LowestLabel: true}} {
+		// a large graph then a small one on the same Session
+		s := NewSession(c)
+		for _, v := range []struct {
+			file    string
+			maxflow uint
+		}{
+			{"_data/BVZ-tsukuba0.max", 34669},
+			{"_data/dimacsMaxf.txt", 15},
+		} {
+			if _, err := s.Run(v.file); err != nil {
+				t.Fatal(err)
+			}
+			if maxflow, _ := s.MaxFlow(); maxflow != v.maxflow {
+				fmt.Println(v.file, "want:", v.maxflow, "got:", maxflow)
+				t.Fatal()
+			}
+		}
+
+		s.Reset()
+		if _, err := s.MaxFlow(); err != ErrNoSolution || s.numNodes != 0 || s.stats.Pushes != 0 {
+			fmt.Println("Session not reset:", err, s.numNodes, s.stats)
+			t.Fatal()
+		}
+	}
+}
+
+func TestRelabelHistory(t *testing.T) {
+	for _, c := range []Context{{CaptureRelabelOrder: true}, {CaptureRelabelOrder: true, LowestLabel: true}} {
+		s := NewSession(c)
+		if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+			t.Fatal(err)
+		}
+		history := s.RelabelHistory()
+		if len(history) == 0 {
+			fmt.Println("no relabels recorded")
+			t.Fatal()
+		}
+
+		var relabels uint
+		labels := make(map[uint]uint)
+		for _, v := range history {
+			if !v.Lift {
+				relabels++
+			}
+			if old, ok := labels[v.Node]; ok && old != v.OldLabel {
+				fmt.Println("node", v.Node, "relabeled from", v.OldLabel, "but was", old)
+				t.Fatal()
+			}
+			labels[v.Node] = v.NewLabel
+		}
+		if relabels != s.stats.Relabels {
+			fmt.Println("want:", s.stats.Relabels, "got:", relabels)
+			t.Fatal()
+		}
+		for n, l := range labels {
+			if s.adjacencyList[n-1].label != l {
+				fmt.Println("node", n, "final label want:", s.adjacencyList[n-1].label, "got:", l)
+				t.Fatal()
+			}
+		}
+	}
+
+	s := NewSession(Context{})
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if s.RelabelHistory() != nil {
+		fmt.Println("want: nil without CaptureRelabelOrder")
+		t.Fatal()
+	}
+}
+
+func TestArcBeforeProblemLine(t *testing.T) {
+	data := "a 1 2 5\np max 2 1\nn 1 s\nn 2 t\n"
+	want := "line 1: arc record before problem 'p' line"
+
+	s := NewSession(Context{})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err == nil || err.Error() != want {
+		fmt.Println("want:", want, "got:", err)
+		t.Fatal()
+	}
+	if _, _, _, _, err := ParseDimacsReader(strings.NewReader(data)); err == nil || err.Error() != want {
+		fmt.Println("ParseDimacsReader want:", want, "got:", err)
+		t.Fatal()
+	}
+
+	want = "line 1: node record before problem 'p' line"
+	data = "n 1 s\np max 2 1\nn 2 t\na 1 2 5\n"
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err == nil || err.Error() != want {
+		fmt.Println("want:", want, "got:", err)
+		t.Fatal()
+	}
+	if _, _, _, _, err := ParseDimacsReader(strings.NewReader(data)); err == nil || err.Error() != want {
+		fmt.Println("ParseDimacsReader want:", want, "got:", err)
+		t.Fatal()
+	}
+}
+
+func TestNoArcs(t *testing.T) {
+	data := "p max 2 0\nn 1 s\nn 2 t\n"
+
+	s := NewSession(Context{})
+	result, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(result, "\n"), "\ns 0\n") {
+		fmt.Println("want: s 0 got:", result)
+		t.Fatal()
+	}
+
+	numNodes, numArcs, n, a, err := ParseDimacsReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = s.RunNAWriter(numNodes, numArcs, n, a, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\ns 0\n") {
+		fmt.Println("want: s 0 got:\n", buf.String())
+		t.Fatal()
+	}
+}
+
+func TestNoSourceOrSink(t *testing.T) {
+	s := NewSession(Context{})
+	data := "p max 3 2\na 1 2 5\na 2 3 5\n"
+	want := "no source - 'n <node> s' - line"
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err == nil || err.Error() != want {
+		fmt.Println("want:", want, "got:", err)
+		t.Fatal()
+	}
+
+	data = "p max 3 2\nn 1 s\na 1 2 5\na 2 3 5\n"
+	want = "no sink - 'n <node> t' - line"
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err == nil || err.Error() != want {
+		fmt.Println("want:", want, "got:", err)
+		t.Fatal()
+	}
+}
+
+func TestMaxFlowRecords(t *testing.T) {
+	s := NewSession(Context{MaxFlowRecords: 3})
+	result, err := s.Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"f 1 2 5", "f 2 5 0", "f 3 4 5", "c ... 5 more flow records (truncated)"}
+	got := result[len(result)-len(want):]
+	if !reflect.DeepEqual(got, want) || result[len(result)-len(want)-1] != "c SRC DST FLOW" {
+		fmt.Println("want:", want, "got:", result)
+		t.Fatal()
+	}
+
+	// the limit is not reached
+	s.SetContext(Context{MaxFlowRecords: 8})
+	if result, err = s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if result[len(result)-1] != "f 1 3 10" {
+		fmt.Println("want: f 1 3 10 got:", result[len(result)-1])
+		t.Fatal()
+	}
+}
+
+func TestCRLF(t *testing.T) {
+	data, err := ioutil.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// CRLF line endings, and no line end on the last line
+	crlf := strings.TrimRight(strings.Replace(string(data), "\n", "\r\n", -1), "\r\n") + "\r"
+
+	s := NewSession(Context{})
+	result, err := s.RunReader(ioutil.NopCloser(strings.NewReader(crlf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := s.Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, want[2:]) { // less "c Data: ..." and "c "
+		fmt.Println("want:", want[2:], "got:", result)
+		t.Fatal()
+	}
+
+	numNodes, numArcs, n, a, err := ParseDimacsReader(strings.NewReader(crlf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	wantNodes, wantArcs, wantn, wanta, err := ParseDimacsReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if numNodes != wantNodes || numArcs != wantArcs || !reflect.DeepEqual(n, wantn) || !reflect.DeepEqual(a, wanta) {
+		fmt.Println("want:", wantn, wanta, "got:", n, a)
+		t.Fatal()
+	}
+}
+
+func TestMergeParallelArcs(t *testing.T) {
+	data := "p max 3 4\nn 1 s\nn 3 t\na 1 2 5\na 1 2 5\na 2 3 7\na 2 3 8\n"
+
+	s := NewSession(Context{})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if flows := s.Flows(); len(flows) != 4 {
+		fmt.Println("want: 4 arcs got:", flows)
+		t.Fatal()
+	}
+
+	want := map[[2]uint]int{{1, 2}: 10, {2, 3}: 15}
+	check := func(src string) {
+		flows := s.Flows()
+		if len(flows) != 2 {
+			fmt.Println(src, "want: 2 arcs got:", flows)
+			t.Fatal()
+		}
+		for _, v := range flows {
+			if want[[2]uint{v.From, v.To}] != v.Capacity || v.Flow != 10 {
+				fmt.Println(src, "want:", want, "got:", flows)
+				t.Fatal()
+			}
+		}
+	}
+
+	s.SetContext(Context{MergeParallelArcs: true})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	check("RunReader")
+
+	numNodes, numArcs, n, a, err := ParseDimacsReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.RunNAWriter(numNodes, numArcs, n, a, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	check("RunNAWriter")
+}
+
+func TestMaxCutArcCapacity(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.MaxCutArcCapacity(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	// cut arcs 1->2, 3->4 and 3->5 all have capacity 5; the first is returned
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.MaxCutArcCapacity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (A{From: 1, To: 2, Capacity: 5, Flow: 5}); a != want {
+		fmt.Println("want:", want, "got:", a)
+		t.Fatal()
+	}
+
+	data := "p max 4 4\nn 1 s\nn 4 t\na 1 2 3\na 1 3 7\na 2 4 10\na 3 4 10\n"
+	if _, err = s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if a, err = s.MaxCutArcCapacity(); err != nil {
+		t.Fatal(err)
+	}
+	if want := (A{From: 1, To: 3, Capacity: 7, Flow: 7}); a != want {
+		fmt.Println("want:", want, "got:", a)
+		t.Fatal()
+	}
+
+	// no flow
+	data = "p max 3 1\nn 1 s\nn 3 t\na 1 2 3\n"
+	if _, err = s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.MaxCutArcCapacity(); err == nil {
+		fmt.Println("want: error for max flow 0")
+		t.Fatal()
+	}
+}
+
+func TestArcCount(t *testing.T) {
+	s := NewSession(Context{})
+	for _, v := range []struct {
+		data, want string
+	}{
+		{"p max 3 3\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", "declared 3 arcs but found 2"},
+		{"p max 3 1\nn 1 s\nn 3 t\na 1 2 5\na 2 3 5\n", "declared 1 arcs but found 2"},
+	} {
+		if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(v.data))); err == nil || err.Error() != v.want {
+			fmt.Println("want:", v.want, "got:", err)
+			t.Fatal()
+		}
+		if _, _, _, _, err := ParseDimacsReader(strings.NewReader(v.data)); err == nil || err.Error() != v.want {
+			fmt.Println("ParseDimacsReader want:", v.want, "got:", err)
+			t.Fatal()
+		}
+	}
+}
+
+func TestPushFn(t *testing.T) {
+	for _, c := range []Context{{}, {LowestLabel: true}, {FifoBuckets: true}} {
+		var pushes uint
+		c.PushFn = func(from, to uint, amount int, upward bool) {
+			if from < 1 || from > 6 || to < 1 || to > 6 || amount < 0 {
+				fmt.Println("bad push:", from, to, amount, upward)
+				t.Fatal()
+			}
+			pushes++
+		}
+		s := NewSession(c)
+		if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if pushes == 0 || pushes != s.stats.Pushes {
+			fmt.Println("want:", s.stats.Pushes, "got:", pushes)
+			t.Fatal()
+		}
+	}
+}
+
+func TestArcNodeRange(t *testing.T) {
+	s := NewSession(Context{})
+	for _, v := range []struct {
+		from, to uint
+		want     string
+	}{
+		{1, 99, "arc (1, 99) has a node out of range 1-3"},
+		{0, 2, "arc (0, 2) has a node out of range 1-3"},
+	} {
+		data := fmt.Sprintf("p max 3 2\nn 1 s\nn 3 t\na %d %d 5\na 2 3 5\n", v.from, v.to)
+		want := "line 4: " + v.want
+		if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err == nil || err.Error() != want {
+			fmt.Println("want:", want, "got:", err)
+			t.Fatal()
+		}
+
+		arcs := []A{{From: v.from, To: v.to, Capacity: 5}, {From: 2, To: 3, Capacity: 5}}
+		if err := s.RunNAWriter(3, 2, []N{{1, "s"}, {3, "t"}}, arcs, ioutil.Discard); err == nil || err.Error() != v.want {
+			fmt.Println("RunNAWriter want:", v.want, "got:", err)
+			t.Fatal()
+		}
+
+		si := NewSessionInitializer(s)
+		si.Init(3, 2)
+		si.SetSource(1)
+		si.SetSink(3)
+		si.AddArc(v.from, v.to, 5)
+		si.AddArc(2, 3, 5)
+		if err := si.Complete(); err == nil || err.Error() != v.want {
+			fmt.Println("AddArc want:", v.want, "got:", err)
+			t.Fatal()
+		}
+	}
+
+	// skipped with SkipBadLines
+	s.SetContext(Context{SkipBadLines: true})
+	result, err := s.RunReader(ioutil.NopCloser(strings.NewReader("p max 3 3\nn 1 s\nn 3 t\na 1 9 5\na 1 2 5\na 2 3 5\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(result, "\n"), "\ns 5\n") || s.stats.SkippedLines != 1 {
+		fmt.Println("want: s 5 and 1 skipped line got:", result)
+		t.Fatal()
+	}
+}
+
+func TestRunBytes(t *testing.T) {
+	data, err := ioutil.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSession(Context{})
+	want, err := s.Run("_data/dimacsMaxf.txt", "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.RunBytes(data, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+}
+
+func TestAssertOptimal(t *testing.T) {
+	s := NewSession(Context{})
+	if err := s.AssertOptimal(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+	for _, c := range []Context{{}, {LowestLabel: true}, {SinkMinimalCut: true}} {
+		s.SetContext(c)
+		if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AssertOptimal(); err != nil {
+			fmt.Println(c, err)
+			t.Fatal()
+		}
+	}
+
+	// 4->6 carries 10 of 15; lose 1 of it
+	for _, a := range s.arcList {
+		if a.from.number == 4 && a.to.number == 6 {
+			a.flow--
+		}
+	}
+	err := s.AssertOptimal()
+	for _, want := range []string{"Flow balance constraint violated in node 4", "flow into the sink 14 does not equal min cut 15"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			fmt.Println("want:", want, "got:", err)
+			t.Fatal()
+		}
+	}
+}
+
+func TestNarrowestCutArc(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.NarrowestCutArc(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	// cut arcs 1->2, 3->4 and 3->5 all have capacity 5; the first is returned
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.NarrowestCutArc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (A{From: 1, To: 2, Capacity: 5, Flow: 5}); a != want {
+		fmt.Println("want:", want, "got:", a)
+		t.Fatal()
+	}
+
+	data := "p max 4 4\nn 1 s\nn 4 t\na 1 2 7\na 1 3 3\na 2 4 10\na 3 4 10\n"
+	if _, err = s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if a, err = s.NarrowestCutArc(); err != nil {
+		t.Fatal(err)
+	}
+	if want := (A{From: 1, To: 3, Capacity: 3, Flow: 3}); a != want {
+		fmt.Println("want:", want, "got:", a)
+		t.Fatal()
+	}
+}
+
+func TestAlgorithmVariant(t *testing.T) {
+	for _, v := range []struct {
+		c              Context
+		want           string
+		label, buckets string
+	}{
+		{Context{}, "highest-label, lifo", "Highest", "LIFO"},
+		{Context{FifoBuckets: true}, "highest-label, fifo", "Highest", "FIFO"},
+		{Context{LowestLabel: true}, "lowest-label, lifo", "Lowest", "LIFO"},
+		{Context{LowestLabel: true, FifoBuckets: true}, "lowest-label, fifo", "Lowest", "FIFO"},
+	} {
+		s := NewSession(v.c)
+		if got := s.AlgorithmVariant(); got != v.want {
+			fmt.Println("want:", v.want, "got:", got)
+			t.Fatal()
+		}
+		result, err := s.Run("_data/dimacsMaxf.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result[7] != "c "+v.label+" label pseudoflow algorithm" || result[8] != "c Using "+v.buckets+" buckets" {
+			fmt.Println(v.want, "got:", result[7:9])
+			t.Fatal()
+		}
+	}
+}
+
+func TestCutSummaryOnly(t *testing.T) {
+	s := NewSession(Context{CutSummaryOnly: true})
+	result, err := s.Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"c Data: _data/dimacsMaxf.txt", "s 15", "c source-set size: 2", "c sink-set size: 4"}
+	if !reflect.DeepEqual(result, want) {
+		fmt.Println("want:", want, "got:", result)
+		t.Fatal()
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	stats := s.Stats()
+	if stats.Pushes == 0 || stats.ArcScans == 0 {
+		fmt.Println("want: pushes and arc scans got:", stats)
+		t.Fatal()
+	}
+	var fromJSON Stats
+	if err := json.Unmarshal([]byte(s.StatsJSON()), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON != stats {
+		fmt.Println("want:", fromJSON, "got:", stats)
+		t.Fatal()
+	}
+}
+
+func TestTimings(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	tm := s.Timings()
+	if tm.Total <= 0 || tm.Total < tm.ReadDimacsFile+tm.SimpleInitialization+tm.FlowPhaseOne+tm.RecoverFlow {
+		fmt.Println("want: total covering the steps got:", tm)
+		t.Fatal()
+	}
+	var fromJSON map[string]string
+	if err := json.Unmarshal([]byte(s.TimerJSON()), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON["total"] != tm.Total.String() || fromJSON["flowPhaseOne"] != tm.FlowPhaseOne.String() {
+		fmt.Println("want:", fromJSON, "got:", tm)
+		t.Fatal()
+	}
+}
+
+func TestRunReadWriterContext(t *testing.T) {
+	data := string(gridDimacs(150))
+
+	s := NewSession(Context{})
+	var full bytes.Buffer
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), &full); err != nil {
+		t.Fatal(err)
+	}
+	fullPushes := s.Stats().Pushes
+
+	// cancel at the first push
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s = NewSession(Context{PushFn: func(from, to uint, amount int, upward bool) { cancel() }})
+	var buf bytes.Buffer
+	if err := s.RunReadWriterContext(ctx, ioutil.NopCloser(strings.NewReader(data)), &buf); err != context.Canceled {
+		fmt.Println("want:", context.Canceled, "got:", err)
+		t.Fatal()
+	}
+	if buf.Len() != 0 || s.FlowBalance() != nil {
+		fmt.Println("cancelled solve reported a result")
+		t.Fatal()
+	}
+	// the partial state is discarded
+	if err := s.RunWriter(&buf); err != ErrNoGraph {
+		fmt.Println("want:", ErrNoGraph, "got:", err)
+		t.Fatal()
+	}
+	if p := s.Stats().Pushes; p >= fullPushes {
+		fmt.Println("pushes want: <", fullPushes, "got:", p)
+		t.Fatal()
+	}
+
+	// not cancelled
+	s = NewSession(Context{})
+	if err := s.RunReadWriterContext(context.Background(), ioutil.NopCloser(strings.NewReader(data)), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != full.String() {
+		fmt.Println("result differs from RunReadWriter")
+		t.Fatal()
+	}
+}
+
+func TestCutArcFraction(t *testing.T) {
+	s := NewSession(Context{})
+	if f := s.CutArcFraction(); f != 0 {
+		fmt.Println("want: 0 got:", f)
+		t.Fatal()
+	}
+
+	// cut arcs 1->2, 3->4 and 3->5 of 8
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if f := s.CutArcFraction(); f != 3.0/8 {
+		fmt.Println("want:", 3.0/8, "got:", f)
+		t.Fatal()
+	}
+}
+
+func TestNewSessionOpts(t *testing.T) {
+	if got, want := NewSessionOpts().ConfigJSON(), NewSession(Context{}).ConfigJSON(); got != want {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+	got := NewSessionOpts(WithLowestLabel(), WithFifoBuckets(), WithDisplayCut()).ConfigJSON()
+	want := NewSession(Context{LowestLabel: true, FifoBuckets: true, DisplayCut: true}).ConfigJSON()
+	if got != want {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+	got = NewSessionOpts(WithDisplayCut()).ConfigJSON()
+	want = NewSession(Context{DisplayCut: true}).ConfigJSON()
+	if got != want {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+}
+
+func TestNewSessionFromJSON(t *testing.T) {
+	c := Context{LowestLabel: true, SinkMinimalCut: true, MaxFlowRecords: 3, WarnWriter: os.Stderr}
+	want := NewSession(c).ConfigJSON()
+	s, err := NewSessionFromJSON([]byte(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.ConfigJSON(); got != want {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+	if _, err = NewSessionFromJSON([]byte(`{"LowestLabel":true,"HighestLabel":true}`)); err == nil {
+		fmt.Println("want: unknown key error")
+		t.Fatal()
+	}
+	if _, err = NewSessionFromJSON([]byte(`{"LowestLabel":`)); err == nil {
+		fmt.Println("want: syntax error")
+		t.Fatal()
+	}
+}
+
+// captureLogger records the lines logged to it.
+type captureLogger struct {
+	lines []string
+}
+
+func (l *captureLogger) Printf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestSetLogger(t *testing.T) {
+	defer func(v uint) { relabelLogInterval = v }(relabelLogInterval)
+	relabelLogInterval = 1
+
+	for _, c := range []Context{{}, {LowestLabel: true}} {
+		var l captureLogger
+		s := NewSession(c)
+		s.SetLogger(&l)
+		if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+			t.Fatal(err)
+		}
+		var gaps, merges, relabels uint
+		for _, v := range l.lines {
+			switch {
+			case strings.HasPrefix(v, "gap: "):
+				gaps++
+			case strings.HasPrefix(v, "merge: "):
+				merges++
+			case strings.HasPrefix(v, "relabels: "):
+				relabels++
+			}
+		}
+		stats := s.Stats()
+		if gaps == 0 || merges != stats.Mergers || relabels != stats.Relabels {
+			fmt.Println("stats:", stats, "logged:\n", strings.Join(l.lines, "\n"))
+			t.Fatal()
+		}
+
+		// no logger
+		s.SetLogger(nil)
+		l.lines = nil
+		if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+			t.Fatal(err)
+		}
+		if len(l.lines) != 0 {
+			fmt.Println("want: nothing logged got:", l.lines)
+			t.Fatal()
+		}
+	}
+}
+
+func TestLinePreprocessor(t *testing.T) {
+	want, err := NewSession(Context{}).Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// semicolon delimited lines with a record type prefix and '#' comments
+	data := `# sample graph
+GRAPH;6;8
+SOURCE;1
+SINK;6
+ARC;1;2;5
+ARC;1;3;15
+ARC;2;4;5
+ARC;2;5;5
+ARC;3;4;5
+ARC;3;5;5
+ARC;4;6;15
+ARC;5;6;5
+`
+	pre := func(line []byte) []byte {
+		if bytes.HasPrefix(line, []byte("#")) {
+			return nil
+		}
+		f := strings.Split(string(line), ";")
+		switch f[0] {
+		case "GRAPH":
+			return []byte("p max " + f[1] + " " + f[2])
+		case "SOURCE":
+			return []byte("n " + f[1] + " s")
+		case "SINK":
+			return []byte("n " + f[1] + " t")
+		}
+		return []byte("a " + strings.Join(f[1:], " "))
+	}
+	s := NewSession(Context{LinePreprocessor: pre})
+	got, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want[2:]) { // less "c Data: ..." and "c "
+		fmt.Println("want:\n", strings.Join(want, "\n"))
+		fmt.Println("got:\n", strings.Join(got, "\n"))
+		t.Fatal()
+	}
+}
+
+func TestWorkPerNode(t *testing.T) {
+	s := NewSession(Context{})
+	if w := s.WorkPerNode(); w != 0 {
+		fmt.Println("want: 0 got:", w)
+		t.Fatal()
+	}
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	// (Pushes + Relabels + ArcScans) / number of nodes
+	stats := s.Stats()
+	want := float64(stats.Pushes+stats.Relabels+stats.ArcScans) / 6
+	if w := s.WorkPerNode(); w <= 0 || w != want {
+		fmt.Println("want:", want, "got:", w)
+		t.Fatal()
+	}
+	var fromJSON struct {
+		WorkPerNode float64 `json:"workPerNode"`
+	}
+	if err := json.Unmarshal([]byte(s.StatsJSON()), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if fromJSON.WorkPerNode != want {
+		fmt.Println("StatsJSON:", s.StatsJSON())
+		t.Fatal()
+	}
+}
+
+func TestSetProgressFunc(t *testing.T) {
+	defer func(v uint) { ctxCheckInterval = v }(ctxCheckInterval)
+
+	for _, v := range []struct {
+		file     string
+		interval uint
+	}{
+		{"_data/dimacsMaxf.txt", 1},
+		{"_data/BVZ-tsukuba0.max", 4096},
+	} {
+		ctxCheckInterval = v.interval
+		var calls []uint
+		var total uint
+		s := NewSession(Context{})
+		s.SetProgressFunc(func(processed, t uint) {
+			calls = append(calls, processed)
+			total = t
+		})
+		if _, err := s.Run(v.file); err != nil {
+			t.Fatal(err)
+		}
+		if len(calls) < 2 || total != s.numNodes || calls[len(calls)-1] < total/2 {
+			fmt.Println(v.file, "calls:", calls, "total:", total, "nodes:", s.numNodes)
+			t.Fatal()
+		}
+		for i := 1; i < len(calls); i++ {
+			if calls[i] < calls[i-1] {
+				fmt.Println(v.file, "processed decreased:", calls[i-1], calls[i])
+				t.Fatal()
+			}
+		}
+	}
+}
+
+func TestCrossCheckMinCut(t *testing.T) {
+	if _, err := NewSession(Context{}).crossCheckMinCut(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	for _, file := range []string{"_data/dimacsMaxf.txt", "_data/BVZ-tsukuba0.max"} {
+		for _, c := range []Context{
+			{},
+			{LowestLabel: true},
+			{FifoBuckets: true},
+			{LowestLabel: true, FifoBuckets: true},
+			{SinkMinimalCut: true},
+		} {
+			if file != "_data/dimacsMaxf.txt" && (c.FifoBuckets || c.SinkMinimalCut) {
+				continue // the large file once per label variant
+			}
+			s := NewSession(c)
+			if _, err := s.Run(file); err != nil {
+				t.Fatal(err)
+			}
+			cut, err := s.crossCheckMinCut()
+			if err != nil {
+				fmt.Println(file, s.ConfigJSON(), err)
+				t.Fatal()
+			}
+			if mf, _ := s.MaxFlow(); uint(cut) != mf {
+				fmt.Println(file, s.ConfigJSON(), "max flow:", mf, "cross-checked cut:", cut)
+				t.Fatal()
+			}
+		}
+	}
+}
+
+func BenchmarkRunNAWriter(b *testing.B) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	fh.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = NewSession(Context{}).RunNAWriter(numNodes, numArcs, n, a, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRunNAWriterReuse solves the sample graph repeatedly with one
+// Session, so the nodes and arcs of each graph are recycled for the next;
+// e.g., go test -bench Reuse -benchtime 100000x.
+func BenchmarkRunNAWriterReuse(b *testing.B) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		b.Fatal(err)
+	}
+	numNodes, numArcs, n, a, err := ParseDimacsReader(fh)
+	fh.Close()
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := NewSession(Context{})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err = s.RunNAWriter(numNodes, numArcs, n, a, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRecycledNodesAndArcs(t *testing.T) {
+	data := []string{
+		"p max 4 5\nn 1 s\nn 4 t\na 1 2 7\na 1 3 3\na 2 4 10\na 3 4 10\na 2 3 4\n",
+		"p max 3 2\nn 3 s\nn 1 t\na 3 2 9\na 2 1 4\n",
+	}
+	want := make([][]string, len(data))
+	for i, v := range data {
+		res, err := NewSession(Context{}).RunReader(ioutil.NopCloser(strings.NewReader(v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = res
+	}
+
+	// the nodes and arcs of each graph are reused, with stale state, for the next
+	s := NewSession(Context{})
+	for i := 0; i < 10; i++ {
+		if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+			t.Fatal(err)
+		}
+		for j, v := range data {
+			got, err := s.RunReader(ioutil.NopCloser(strings.NewReader(v)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want[j]) {
+				fmt.Println("run", i, "want:\n", strings.Join(want[j], "\n"))
+				fmt.Println("got:\n", strings.Join(got, "\n"))
+				t.Fatal()
+			}
+		}
+	}
+}
+
+func TestSetScanBufferSize(t *testing.T) {
+	long := "c " + strings.Repeat("x", 200*1024) + "\n"
+	data := "p max 3 2\n" + long + "n 1 s\nn 3 t\na 1 2 5\na 2 3 5" // no final '\n'
+
+	s := NewSession(Context{})
+	if _, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	if mf, _ := s.MaxFlow(); mf != 5 {
+		fmt.Println("want: 5 got:", mf)
+		t.Fatal()
+	}
+
+	s.SetScanBufferSize(1024)
+	_, err := s.RunReader(ioutil.NopCloser(strings.NewReader(data)))
+	if err == nil || !strings.Contains(err.Error(), "line 2 is longer than the scan buffer size 1024") {
+		fmt.Println("want: line 2 too long got:", err)
+		t.Fatal()
+	}
+	s.SetScanBufferSize(len(long))
+	if _, err = s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	s.SetScanBufferSize(0)
+	if _, err = s.RunReader(ioutil.NopCloser(strings.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// gridDimacs returns DIMACS input for a grid of 'n' x 'n' nodes with the
+// source joined to the first column and the last column to the sink.
+func gridDimacs(n int) []byte {
+	node := func(r, c int) int { return r*n + c + 1 }
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "p max %d %d\nn %d s\nn %d t\n", n*n+2, 2*n+2*n*(n-1)+n*(n-1), n*n+1, n*n+2)
+	for r := 0; r < n; r++ {
+		fmt.Fprintf(&b, "a %d %d %d\n", n*n+1, node(r, 0), 1000)
+		fmt.Fprintf(&b, "a %d %d %d\n", node(r, n-1), n*n+2, 1000)
+		for c := 0; c < n-1; c++ {
+			fmt.Fprintf(&b, "a %d %d %d\n", node(r, c), node(r, c+1), 1+(r*c)%17)
+			fmt.Fprintf(&b, "a %d %d %d\n", node(r, c+1), node(r, c), 1+(r+c)%13)
+		}
+		if r < n-1 {
+			for c := 0; c < n; c++ {
+				fmt.Fprintf(&b, "a %d %d %d\n", node(r, c), node(r+1, c), 1+(r+2*c)%11)
+			}
+		}
+	}
+	return b.Bytes()
+}
+
+func BenchmarkReadDimacsFile(b *testing.B) {
+	data := gridDimacs(300)
+	s := NewSession(Context{})
+	s.SetScanBufferSize(1 << 20)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.readDimacsFile(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	s := NewSession(Context{})
+	if _, _, _, err := s.Verify(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	feasible, optimal, violations, err := s.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feasible || !optimal || len(violations) != 0 {
+		fmt.Println("want: true true [] got:", feasible, optimal, violations)
+		t.Fatal()
+	}
+
+	// the violations are the comments checkOptimality writes
+	s.arcList[0].flow = s.arcList[0].capacity + 1
+	if feasible, optimal, violations, _ = s.Verify(); feasible || len(violations) == 0 {
+		fmt.Println("want: infeasible got:", feasible, optimal, violations)
+		t.Fatal()
+	}
+	var buf bytes.Buffer
+	if err = s.checkOptimality(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range violations {
+		if !strings.Contains(buf.String(), "c "+v+"\n") {
+			fmt.Println("want:", v, "got:", buf.String())
+			t.Fatal()
+		}
+	}
+}
+
+func TestUseArcPrefix(t *testing.T) {
+	want, err := NewSession(Context{}).Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewSession(Context{UseArcPrefix: true}).Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+	var arcs int
+	for i, v := range want {
+		if strings.HasPrefix(v, "f ") {
+			arcs++
+			v = "a " + v[2:]
+		}
+		if got[i] != v {
+			fmt.Println("want:", v, "got:", got[i])
+			t.Fatal()
+		}
+	}
+	if arcs != 8 {
+		fmt.Println("want: 8 flow records got:", arcs)
+		t.Fatal()
+	}
+}
+
+func TestSortOutput(t *testing.T) {
+	got, err := NewSession(Context{SortOutput: true}).Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flows []string
+	for _, v := range got {
+		if strings.HasPrefix(v, "f ") {
+			flows = append(flows, v)
+		}
+	}
+	want := []string{"f 1 2 5", "f 1 3 10", "f 2 4 5", "f 2 5 0", "f 3 4 5", "f 3 5 5", "f 4 6 10", "f 5 6 5"}
+	if !reflect.DeepEqual(flows, want) {
+		fmt.Println("want:", want, "got:", flows)
+		t.Fatal()
+	}
+
+	// truncated after sorting
+	got, err = NewSession(Context{SortOutput: true, MaxFlowRecords: 2}).Run("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tail := got[len(got)-3:]; !reflect.DeepEqual(tail, []string{"f 1 2 5", "f 1 3 10", "c ... 6 more flow records (truncated)"}) {
+		fmt.Println("got:", tail)
+		t.Fatal()
+	}
+}
+
+func TestCutArcs(t *testing.T) {
+	s := NewSession(Context{})
+	if s.CutArcs() != nil {
+		fmt.Println("want: nil before Run")
+		t.Fatal()
+	}
+
+	for _, file := range []string{"_data/dimacsMaxf.txt", "_data/BVZ-tsukuba0.max"} {
+		if _, err := s.Run(file); err != nil {
+			t.Fatal(err)
+		}
+		var sum int
+		for _, a := range s.CutArcs() {
+			if a.Flow != a.Capacity {
+				fmt.Println(file, "cut arc not saturated:", a)
+				t.Fatal()
+			}
+			sum += a.Capacity
+		}
+		if mf, _ := s.MaxFlow(); uint(sum) != mf {
+			fmt.Println(file, "want:", mf, "got:", sum)
+			t.Fatal()
+		}
+	}
+
+	// cut arcs 1->2, 3->4 and 3->5
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	want := []A{{From: 1, To: 2, Capacity: 5, Flow: 5}, {From: 3, To: 4, Capacity: 5, Flow: 5}, {From: 3, To: 5, Capacity: 5, Flow: 5}}
+	if got := s.CutArcs(); !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+}
+
+func TestSolve(t *testing.T) {
+	fh, err := os.Open("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	s := NewSession(Context{})
+	sol, err := s.Solve(fh, "sample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.MaxFlow != 15 || sol.Header != "sample" || len(sol.Flows) != 8 || sol.Stats != s.Stats() {
+		fmt.Printf("got: %+v\n", sol)
+		t.Fatal()
+	}
+
+	// feasible flows
+	balance := make(map[uint]int)
+	for _, a := range sol.Flows {
+		if a.Flow < 0 || a.Flow > a.Capacity {
+			fmt.Println("infeasible flow:", a)
+			t.Fatal()
+		}
+		balance[a.From] -= a.Flow
+		balance[a.To] += a.Flow
+	}
+	for n, v := range balance {
+		if n != 1 && n != 6 && v != 0 {
+			fmt.Println("node", n, "has excess", v)
+			t.Fatal()
+		}
+	}
+	if balance[6] != int(sol.MaxFlow) {
+		fmt.Println("flow into the sink want:", sol.MaxFlow, "got:", balance[6])
+		t.Fatal()
+	}
+
+	// the cut arcs leave the source set and their capacities are the max flow
+	in := make(map[uint]bool)
+	for _, n := range sol.SourceSet {
+		in[n] = true
+	}
+	var cut int
+	for _, a := range sol.CutArcs {
+		if !in[a.From] || in[a.To] {
+			fmt.Println("arc doesn't cross the cut:", a, sol.SourceSet)
+			t.Fatal()
+		}
+		cut += a.Capacity
+	}
+	if uint(cut) != sol.MaxFlow {
+		fmt.Println("cut want:", sol.MaxFlow, "got:", cut)
+		t.Fatal()
+	}
+
+	if _, err = s.Solve(strings.NewReader("p max 2 1\n")); err == nil {
+		fmt.Println("want: error for bad input")
+		t.Fatal()
+	}
+}
+
+func TestMultiTerminal(t *testing.T) {
+	// sources 1 and 2, sinks 5 and 6; the min cut nearest the sinks is the
+	// arcs into 3 and (4, 6)
+	data := multiTerminalData
+
+	s := NewSession(Context{})
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard); !errors.Is(err, ErrTerminals) {
+		fmt.Println("want:", ErrTerminals, "got:", err)
+		t.Fatal()
+	}
+
+	s = NewSession(Context{MultiTerminal: true, SinkMinimalCut: true})
+	var buf bytes.Buffer
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 8 {
+		fmt.Println("want: 8 got:", s.maxFlow)
+		t.Fatal()
+	}
+	if flows := s.Flows(); len(flows) != 6 {
+		fmt.Println("want 6 flows, got:", flows)
+		t.Fatal()
+	}
+	if got := strings.Count(buf.String(), "\nf "); got != 6 {
+		fmt.Println("want 6 f lines, got:\n", buf.String())
+		t.Fatal()
+	}
+	if cut := s.Cut(); !reflect.DeepEqual(cut, []uint{1, 2, 4}) {
+		fmt.Println("want: [1 2 4] got:", cut)
+		t.Fatal()
+	}
+	if sink := s.SinkSet(); !reflect.DeepEqual(sink, []uint{3, 5, 6}) {
+		fmt.Println("want: [3 5 6] got:", sink)
+		t.Fatal()
+	}
+
+	// source 1 limited to 1
+	limited := strings.Replace(data, "n 1 s\n", "n 1 s 1\n", 1)
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(limited)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 5 {
+		fmt.Println("want: 5 got:", s.maxFlow)
+		t.Fatal()
+	}
+
+	both := strings.Replace(data, "n 6 t\n", "n 6 t\nn 2 t\n", 1)
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(both)), ioutil.Discard); !errors.Is(err, ErrTerminals) {
+		fmt.Println("want:", ErrTerminals, "got:", err)
+		t.Fatal()
+	}
+}
+
+// multiTerminalData has sources 1 and 2 and sinks 5 and 6; the max flow is 8.
+const multiTerminalData = "p max 6 6\nn 1 s\nn 2 s\nn 5 t\nn 6 t\n" +
+	"a 1 3 4\na 2 3 3\na 2 4 2\na 3 5 5\na 3 6 4\na 4 6 1\n"
+
+// inputFlows reports whether Flows has the 6 arcs of multiTerminalData and
+// no arcs of the super-source or super-sink.
+func inputFlows(s *Session) bool {
+	flows := s.Flows()
+	for _, a := range flows {
+		if a.From > 6 || a.To > 6 {
+			return false
+		}
+	}
+	return len(flows) == 6
+}
+
+func TestMultiTerminalReload(t *testing.T) {
+	s := NewSession(Context{MultiTerminal: true, SkipBadLines: true})
+	bad := strings.Replace(multiTerminalData, "a 1 3 4\n", "a 1 3 4\nx junk\n", 1)
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(bad)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.Stats().SkippedLines != 1 || !inputFlows(s) {
+		fmt.Println("skipped lines - want: 1 got:", s.Stats().SkippedLines, "flows:", s.Flows())
+		t.Fatal()
+	}
+
+	// SaveState and LoadState
+	var buf bytes.Buffer
+	if err := s.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inputFlows(loaded) {
+		fmt.Println("LoadState - got:", loaded.Flows())
+		t.Fatal()
+	}
+
+	// CloneWithContext
+	clone, err := s.CloneWithContext(Context{LowestLabel: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = clone.RunWriter(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if clone.maxFlow != 8 || !inputFlows(clone) {
+		fmt.Println("CloneWithContext - got:", clone.maxFlow, clone.Flows())
+		t.Fatal()
+	}
+
+	// DumpJSON and LoadDumpJSON
+	dump, err := s.DumpJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err = LoadDumpJSON(dump); err != nil {
+		t.Fatal(err)
+	}
+	if err = loaded.RunWriter(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.maxFlow != 8 || !inputFlows(loaded) {
+		fmt.Println("LoadDumpJSON - got:", loaded.maxFlow, loaded.Flows())
+		t.Fatal()
+	}
+
+	// ApplyPatch; (4, 6) is in the min cut
+	if err = s.ApplyPatch(strings.NewReader("~ 4 6 2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.RunWriter(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 9 || !inputFlows(s) {
+		fmt.Println("ApplyPatch - got:", s.maxFlow, s.Flows())
+		t.Fatal()
+	}
+}
+
+func TestUndirected(t *testing.T) {
+	// the edges (4, 3) and (4, 2) only carry flow to the sink if undirected;
+	// then the min cut is the edges into 4
+	data := "p max 4 5\nn 1 s\nn 4 t\na 1 2 3\na 1 3 4\na 2 3 5\na 4 3 4\na 4 2 2\n"
+
+	s := NewSession(Context{})
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 0 {
+		fmt.Println("want: 0 got:", s.maxFlow)
+		t.Fatal()
+	}
+
+	s = NewSession(Context{Undirected: true})
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 6 {
+		fmt.Println("want: 6 got:", s.maxFlow)
+		t.Fatal()
+	}
+	if s.numArcs != 10 {
+		fmt.Println("want: 10 arcs got:", s.numArcs)
+		t.Fatal()
+	}
+	if cut := s.Cut(); !reflect.DeepEqual(cut, []uint{1, 2, 3}) {
+		fmt.Println("want: [1 2 3] got:", cut)
+		t.Fatal()
+	}
+	var capacity int
+	for _, a := range s.CutArcs() {
+		if a.To != 4 {
+			fmt.Println("want arcs into 4, got:", s.CutArcs())
+			t.Fatal()
+		}
+		capacity += a.Capacity
+	}
+	if capacity != 6 {
+		fmt.Println("want cut capacity 6, got:", capacity)
+		t.Fatal()
+	}
+	if feasible, optimal, violations, err := s.Verify(); err != nil || !feasible || !optimal {
+		fmt.Println(feasible, optimal, violations, err)
+		t.Fatal()
+	}
+
+	// too few edges for the 'p' line
+	short := strings.Replace(data, "a 4 2 2\n", "", 1)
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(short)), ioutil.Discard); !errors.Is(err, ErrArcCount) {
+		fmt.Println("want:", ErrArcCount, "got:", err)
+		t.Fatal()
+	}
+
+	// the same graph with AddUndirectedArc on a directed Session
+	s = NewSession(Context{})
+	si := NewSessionInitializer(s)
+	si.Init(4, 10)
+	si.SetSource(1)
+	si.SetSink(4)
+	si.AddUndirectedArc(1, 2, 3)
+	si.AddUndirectedArc(1, 3, 4)
+	si.AddUndirectedArc(2, 3, 5)
+	si.AddUndirectedArc(4, 3, 4)
+	si.AddUndirectedArc(4, 2, 2)
+	if err := si.Complete(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.solve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s.maxFlow != 6 {
+		fmt.Println("want: 6 got:", s.maxFlow)
+		t.Fatal()
+	}
+}
+
+func TestCapacityOverflow(t *testing.T) {
+	// three source arcs near math.MaxInt32 sum past it
+	c := math.MaxInt32 - 1
+	data := fmt.Sprintf("p max 5 6\nn 1 s\nn 5 t\na 1 2 %d\na 1 3 %d\na 1 4 %d\na 2 5 %d\na 3 5 %d\na 4 5 %d\n", c, c, c, c, c, c)
+	s := NewSession(Context{})
+	err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard)
+	if strconv.IntSize == 32 {
+		if err != ErrCapacityOverflow {
+			fmt.Println("want:", ErrCapacityOverflow, "got:", err)
+			t.Fatal()
+		}
+	} else {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.maxFlow != 3*c {
+			fmt.Println("want:", 3*c, "got:", s.maxFlow)
+			t.Fatal()
+		}
+	}
+
+	// two source arcs near math.MaxInt overflow on any target
+	c = math.MaxInt - 1
+	data = fmt.Sprintf("p max 3 3\nn 1 s\nn 3 t\na 1 2 %d\na 1 3 %d\na 2 3 %d\n", c, c, c)
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard); err != ErrCapacityOverflow {
+		fmt.Println("want:", ErrCapacityOverflow, "got:", err)
+		t.Fatal()
+	}
+}
+
+func TestExcessOverflow(t *testing.T) {
+	// parallel source arcs into node 2 overflow its excess
+	c := math.MaxInt - 1
+	data := fmt.Sprintf("p max 3 3\nn 1 s\nn 3 t\na 1 2 %d\na 1 2 %d\na 2 3 %d\n", c, c, c)
+	s := NewSession(Context{})
+	err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard)
+	if !errors.Is(err, ErrCapacityOverflow) || !strings.Contains(err.Error(), "excess overflow at node 2") {
+		fmt.Println("want: excess overflow at node 2 got:", err)
+		t.Fatal()
+	}
+
+	// parallel sink arcs out of node 2
+	data = fmt.Sprintf("p max 3 3\nn 1 s\nn 3 t\na 1 2 1\na 2 3 %d\na 2 3 %d\n", c, c)
+	err = s.RunReadWriter(ioutil.NopCloser(strings.NewReader(data)), ioutil.Discard)
+	if !errors.Is(err, ErrCapacityOverflow) || !strings.Contains(err.Error(), "excess overflow at node 2") {
+		fmt.Println("want: excess overflow at node 2 got:", err)
+		t.Fatal()
+	}
+}
+
+func TestGraphSize(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	got := []uint{s.NumNodes(), s.NumArcs(), s.Source(), s.Sink()}
+	if want := []uint{6, 8, 1, 6}; !reflect.DeepEqual(got, want) {
+		fmt.Println("want:", want, "got:", got)
+		t.Fatal()
+	}
+}
+
+func TestRunStream(t *testing.T) {
+	sample, err := ioutil.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := "c second problem\np max 3 2\nn 1 s\nn 3 t\na 1 2 5\na 2 3 4\n"
+	stream := string(sample) + "\n\n" + second
+
+	s := NewSession(Context{})
+	sols, err := s.RunStream(strings.NewReader(stream))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sols) != 2 || sols[0].MaxFlow != 15 || sols[1].MaxFlow != 4 || len(sols[1].Flows) != 2 {
+		fmt.Printf("got: %+v\n", sols)
+		t.Fatal()
+	}
+
+	// the second problem is missing an arc
+	bad := strings.Replace(stream, "a 2 3 4\n", "", 1)
+	sols, err = s.RunStream(strings.NewReader(bad))
+	if err == nil || !strings.HasPrefix(err.Error(), "problem 2:") || len(sols) != 1 {
+		fmt.Println("want: problem 2 error and 1 solution, got:", err, len(sols))
+		t.Fatal()
+	}
+}
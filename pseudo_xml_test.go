@@ -0,0 +1,79 @@
+//go:build pseudoxml
+
+package pseudo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestResultXML(t *testing.T) {
+	s := NewSession(Context{})
+	if _, err := s.ResultXML(); err != ErrNoSolution {
+		fmt.Println("want:", ErrNoSolution, "got:", err)
+		t.Fatal()
+	}
+
+	if _, err := s.Run("_data/dimacsMaxf.txt"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.ResultXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(string(b))
+
+	// round trip
+	var res xmlResult
+	if err = xml.Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.MaxFlow != 15 {
+		fmt.Println("maxflow - want: 15 got:", res.MaxFlow)
+		t.Fatal()
+	}
+	if len(res.Flows) != 8 {
+		fmt.Println("flows - want: 8 got:", len(res.Flows))
+		t.Fatal()
+	}
+	for i, f := range res.Flows {
+		if f.From != s.arcList[i].from.number || f.To != s.arcList[i].to.number || f.Flow != s.arcList[i].flow {
+			fmt.Println("flow", i, "got:", f)
+			t.Fatal()
+		}
+	}
+	if len(res.Cut) != 2 || res.Cut[0] != 1 || res.Cut[1] != 3 {
+		fmt.Println("cut - want: [1 3] got:", res.Cut)
+		t.Fatal()
+	}
+}
+
+func TestResultXMLMultiTerminal(t *testing.T) {
+	s := NewSession(Context{MultiTerminal: true})
+	if err := s.RunReadWriter(ioutil.NopCloser(strings.NewReader(multiTerminalData)), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.ResultXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res xmlResult
+	if err = xml.Unmarshal(b, &res); err != nil {
+		t.Fatal(err)
+	}
+	// no arcs of the super-source or super-sink
+	flows := s.Flows()
+	if res.MaxFlow != 8 || len(res.Flows) != len(flows) {
+		fmt.Println(string(b))
+		t.Fatal()
+	}
+	for i, f := range res.Flows {
+		if f.From != flows[i].From || f.To != flows[i].To || f.Flow != flows[i].Flow {
+			fmt.Println("flow", i, "got:", f)
+			t.Fatal()
+		}
+	}
+}
@@ -5,6 +5,7 @@ package pseudo
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
 )
@@ -24,3 +25,39 @@ func TestReadWriter(t *testing.T) {
 	}
 	fmt.Println(string(output.Bytes()))
 }
+
+// failWriter fails once more than 'n' bytes have been written.
+type failWriter struct {
+	n int
+}
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		written := w.n
+		w.n = 0
+		return written, fmt.Errorf("write failed")
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestReadWriterError(t *testing.T) {
+	data, err := os.ReadFile("_data/dimacsMaxf.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := new(bytes.Buffer)
+	if err = NewSession(Context{}).RunReadWriter(ioutil.NopCloser(bytes.NewReader(data)), full); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failure anywhere in the output, including the solution
+	// check lines, is returned
+	for n := 0; n < full.Len(); n++ {
+		err = NewSession(Context{}).RunReadWriter(ioutil.NopCloser(bytes.NewReader(data)), &failWriter{n})
+		if err == nil {
+			fmt.Println("no error for a writer failing after", n, "of", full.Len(), "bytes")
+			t.Fatal()
+		}
+	}
+}